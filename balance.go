@@ -0,0 +1,342 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// BalanceStrategy 负载均衡策略
+type BalanceStrategy string
+
+const (
+	// BalanceRoundRobin 轮询（默认）
+	BalanceRoundRobin BalanceStrategy = "round_robin"
+	// BalanceLeastInFlight 选择进行中请求最少的后端
+	BalanceLeastInFlight BalanceStrategy = "least_in_flight"
+	// BalanceWeighted 按权重平滑轮询
+	BalanceWeighted BalanceStrategy = "weighted"
+)
+
+// BalancePolicy 负载均衡配置
+type BalancePolicy struct {
+	// Strategy 选择后端的策略，为空时使用轮询
+	Strategy BalanceStrategy `yaml:"strategy"`
+	// Weights 各后端的权重，与后端（或 base_urls）按顺序对应，只用于 weighted，为空时权重都为 1
+	Weights []int `yaml:"weights"`
+}
+
+// Validate 检查策略配置，backends 为后端数量
+func (p BalancePolicy) Validate(backends int) error {
+	switch p.Strategy {
+	case "", BalanceRoundRobin, BalanceLeastInFlight, BalanceWeighted:
+	default:
+		return fmt.Errorf("%w: unknown balance strategy: %s", ErrInvalidConfig, p.Strategy)
+	}
+	if len(p.Weights) == 0 {
+		return nil
+	}
+	if len(p.Weights) != backends {
+		return fmt.Errorf("%w: got %d weights for %d backends", ErrInvalidConfig, len(p.Weights), backends)
+	}
+	for i, weight := range p.Weights {
+		if weight <= 0 {
+			return fmt.Errorf("%w: weight of backend %d must be positive", ErrInvalidConfig, i)
+		}
+	}
+	return nil
+}
+
+// LoadBalancedEmbedder 将请求分散到多个后端的嵌入服务
+// Embed 把一批文本切分给多个后端并发处理，再按原顺序拼接结果
+// 熔断中的后端（CircuitBreaker）不参与选择；后端返回 ErrUnavailable（包括 ErrCircuitOpen）时换用其他后端
+type LoadBalancedEmbedder struct {
+	backends  []*balancedBackend
+	strategy  BalanceStrategy
+	dimension *dimensionGuard
+
+	// next 轮询计数
+	next atomic.Uint64
+	// mu 保护平滑加权轮询的 current
+	mu sync.Mutex
+}
+
+// balancedBackend 单个后端及其负载状态
+type balancedBackend struct {
+	embedder Embedder
+	weight   int
+	current  int
+	inFlight atomic.Int64
+}
+
+// NewLoadBalancedEmbedder 创建负载均衡嵌入服务
+// 所有后端必须使用相同的模型和维度；未知维度的延迟初始化后端在返回向量后检查，维度不一致时返回 ErrInvalidResponse
+func NewLoadBalancedEmbedder(backends []Embedder, policy BalancePolicy) (*LoadBalancedEmbedder, error) {
+	dimension, err := checkBackends(backends)
+	if err != nil {
+		return nil, err
+	}
+	if err := policy.Validate(len(backends)); err != nil {
+		return nil, err
+	}
+
+	strategy := policy.Strategy
+	if strategy == "" {
+		strategy = BalanceRoundRobin
+	}
+
+	lb := &LoadBalancedEmbedder{strategy: strategy, dimension: newDimensionGuard(dimension)}
+	for i, backend := range backends {
+		weight := 1
+		if len(policy.Weights) > 0 {
+			weight = policy.Weights[i]
+		}
+		lb.backends = append(lb.backends, &balancedBackend{embedder: backend, weight: weight})
+	}
+	return lb, nil
+}
+
+// Embed 批量嵌入多个文本，切分给多个后端并发处理
+func (lb *LoadBalancedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return lb.split(ctx, texts, func(ctx context.Context, e Embedder, texts []string) ([][]float32, error) {
+		return e.Embed(ctx, texts)
+	})
+}
+
+// EmbedSingle 嵌入单个文本
+func (lb *LoadBalancedEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	var result []float32
+	err := lb.with(ctx, func(e Embedder) error {
+		var err error
+		if result, err = e.EmbedSingle(ctx, text); err != nil {
+			return err
+		}
+		return lb.dimension.check(result)
+	})
+	return result, err
+}
+
+// BatchEmbed 分批处理大量文本，每批交给一个后端，批次之间按后端数量并发
+func (lb *LoadBalancedEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	if batchSize <= 0 {
+		batchSize = len(texts)
+	}
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	allEmbeddings := make([][]float32, len(texts))
+	batches := (len(texts) + batchSize - 1) / batchSize
+	err := runParallel(ctx, batches, len(lb.backends), func(ctx context.Context, b int) error {
+		start := b * batchSize
+		end := start + batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		return lb.with(ctx, func(e Embedder) error {
+			embeddings, err := e.Embed(ctx, texts[start:end])
+			if err != nil {
+				return err
+			}
+			if len(embeddings) != end-start {
+				return fmt.Errorf("%w: expected %d embeddings, got %d", ErrInvalidResponse, end-start, len(embeddings))
+			}
+			if err := lb.dimension.check(embeddings...); err != nil {
+				return err
+			}
+			copy(allEmbeddings[start:end], embeddings)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return allEmbeddings, nil
+}
+
+// EmbedQuery 以查询模式嵌入文本，后端不支持时直接嵌入
+func (lb *LoadBalancedEmbedder) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	var result []float32
+	err := lb.with(ctx, func(e Embedder) error {
+		var err error
+		if result, err = EmbedQuery(ctx, e, query); err != nil {
+			return err
+		}
+		return lb.dimension.check(result)
+	})
+	return result, err
+}
+
+// EmbedDocuments 以文档模式嵌入多个文本，切分给多个后端并发处理
+func (lb *LoadBalancedEmbedder) EmbedDocuments(ctx context.Context, documents []string) ([][]float32, error) {
+	return lb.split(ctx, documents, func(ctx context.Context, e Embedder, texts []string) ([][]float32, error) {
+		return EmbedDocuments(ctx, e, texts)
+	})
+}
+
+// GetDimension 获取嵌入维度，取第一个已知维度的后端
+func (lb *LoadBalancedEmbedder) GetDimension() int {
+	for _, b := range lb.backends {
+		if d := b.embedder.GetDimension(); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// GetModel 获取模型名称
+func (lb *LoadBalancedEmbedder) GetModel() string {
+	return lb.backends[0].embedder.GetModel()
+}
+
+// Health 健康检查，所有后端都可用时返回 nil
+func (lb *LoadBalancedEmbedder) Health(ctx context.Context) error {
+	var errs []error
+	for i, b := range lb.backends {
+		if err := b.embedder.Health(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("backend %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// InFlight 返回第 i 个后端进行中的请求数
+func (lb *LoadBalancedEmbedder) InFlight(i int) int {
+	return int(lb.backends[i].inFlight.Load())
+}
+
+// split 将文本切成与后端数量相同的连续分片并发处理，按原顺序拼接（私有方法）
+func (lb *LoadBalancedEmbedder) split(ctx context.Context, texts []string, fn func(context.Context, Embedder, []string) ([][]float32, error)) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	parts := len(lb.backends)
+	if parts > len(texts) {
+		parts = len(texts)
+	}
+
+	result := make([][]float32, len(texts))
+	err := runParallel(ctx, parts, parts, func(ctx context.Context, p int) error {
+		// 前 len(texts)%parts 个分片多分一个
+		start := p*(len(texts)/parts) + min(p, len(texts)%parts)
+		end := start + len(texts)/parts
+		if p < len(texts)%parts {
+			end++
+		}
+
+		return lb.with(ctx, func(e Embedder) error {
+			embeddings, err := fn(ctx, e, texts[start:end])
+			if err != nil {
+				return err
+			}
+			if len(embeddings) != end-start {
+				return fmt.Errorf("%w: expected %d embeddings, got %d", ErrInvalidResponse, end-start, len(embeddings))
+			}
+			if err := lb.dimension.check(embeddings...); err != nil {
+				return err
+			}
+			copy(result[start:end], embeddings)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// with 选择一个后端执行 fn，期间计入该后端的进行中请求数
+// 后端不可用时换用尚未尝试的后端，全部失败时返回最后一个错误（私有方法）
+func (lb *LoadBalancedEmbedder) with(ctx context.Context, fn func(Embedder) error) error {
+	tried := make([]bool, len(lb.backends))
+	var err error
+	for range lb.backends {
+		i := lb.pick(tried)
+		tried[i] = true
+		b := lb.backends[i]
+
+		b.inFlight.Add(1)
+		err = fn(b.embedder)
+		b.inFlight.Add(-1)
+		if err == nil || ctx.Err() != nil || !errors.Is(err, ErrUnavailable) {
+			return err
+		}
+	}
+	return err
+}
+
+// pick 按策略从尚未尝试的后端中选择一个，优先选择未熔断的后端，返回下标（私有方法）
+func (lb *LoadBalancedEmbedder) pick(tried []bool) int {
+	if len(lb.backends) == 1 {
+		return 0
+	}
+
+	candidates := make([]bool, len(lb.backends))
+	found := false
+	for i, b := range lb.backends {
+		if !tried[i] && backendAvailable(b.embedder) {
+			candidates[i], found = true, true
+		}
+	}
+	if !found {
+		// 剩余后端都已熔断时仍选择一个，由其返回 CircuitOpenError
+		for i := range lb.backends {
+			candidates[i] = !tried[i]
+		}
+	}
+
+	switch lb.strategy {
+	case BalanceLeastInFlight:
+		// 从轮询位置开始比较，负载相同时依次使用各后端
+		offset := int(lb.next.Add(1) - 1)
+		best := -1
+		for j := range lb.backends {
+			i := (offset + j) % len(lb.backends)
+			if candidates[i] && (best < 0 || lb.backends[i].inFlight.Load() < lb.backends[best].inFlight.Load()) {
+				best = i
+			}
+		}
+		return best
+
+	case BalanceWeighted:
+		// 平滑加权轮询：每次候选后端加上自身权重，选出最大者并减去候选的总权重
+		lb.mu.Lock()
+		defer lb.mu.Unlock()
+
+		best, total := -1, 0
+		for i, b := range lb.backends {
+			if !candidates[i] {
+				continue
+			}
+			b.current += b.weight
+			total += b.weight
+			if best < 0 || b.current > lb.backends[best].current {
+				best = i
+			}
+		}
+		lb.backends[best].current -= total
+		return best
+
+	default:
+		offset := int((lb.next.Add(1) - 1) % uint64(len(lb.backends)))
+		for j := range lb.backends {
+			if i := (offset + j) % len(lb.backends); candidates[i] {
+				return i
+			}
+		}
+		return offset
+	}
+}
+
+// backendAvailable 后端是熔断器时检查是否可以放行请求
+func backendAvailable(e Embedder) bool {
+	if breaker, ok := e.(*CircuitBreaker); ok {
+		return breaker.available()
+	}
+	return true
+}
@@ -0,0 +1,267 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// taggedEmbedder 向量为 [后端编号, 文本数值] 的测试后端
+type taggedEmbedder struct {
+	MockEmbedder
+	id float32
+
+	mu      sync.Mutex
+	calls   int
+	started chan struct{}
+	release chan struct{}
+}
+
+func (e *taggedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	e.mu.Lock()
+	e.calls++
+	e.mu.Unlock()
+
+	if e.release != nil {
+		e.started <- struct{}{}
+		<-e.release
+	}
+
+	result := make([][]float32, len(texts))
+	for i, text := range texts {
+		value, _ := strconv.Atoi(text)
+		result[i] = []float32{e.id, float32(value)}
+	}
+	return result, nil
+}
+
+func (e *taggedEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := e.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (e *taggedEmbedder) GetDimension() int {
+	return 2
+}
+
+// callCount 读取调用次数
+func (e *taggedEmbedder) callCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls
+}
+
+// numbers 生成 "0".."n-1"
+func numbers(n int) []string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = strconv.Itoa(i)
+	}
+	return texts
+}
+
+func TestLoadBalancedEmbedderSplitsInOrder(t *testing.T) {
+	backends := []*taggedEmbedder{{id: 0}, {id: 1}, {id: 2}}
+	lb, err := NewLoadBalancedEmbedder([]Embedder{backends[0], backends[1], backends[2]}, BalancePolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	embeddings, err := lb.Embed(context.Background(), numbers(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	used := make(map[float32]bool)
+	for i, embedding := range embeddings {
+		if embedding[1] != float32(i) {
+			t.Fatalf("Expected embedding %d in position %d, got %v", int(embedding[1]), i, embedding)
+		}
+		used[embedding[0]] = true
+	}
+	if len(used) != 3 {
+		t.Errorf("Expected texts to be spread across 3 backends, got %v", used)
+	}
+
+	embeddings, err = lb.BatchEmbed(context.Background(), numbers(10), 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, embedding := range embeddings {
+		if embedding[1] != float32(i) {
+			t.Fatalf("BatchEmbed: expected embedding %d in position %d, got %v", int(embedding[1]), i, embedding)
+		}
+	}
+	// 3 个分片 + 4 个批次，轮询下每个后端 2 到 3 次
+	for i, b := range backends {
+		if calls := b.callCount(); calls < 2 || calls > 3 {
+			t.Errorf("Backend %d: unexpected call count %d", i, calls)
+		}
+	}
+}
+
+func TestLoadBalancedEmbedderWeighted(t *testing.T) {
+	heavy, light := &taggedEmbedder{id: 0}, &taggedEmbedder{id: 1}
+	lb, err := NewLoadBalancedEmbedder([]Embedder{heavy, light}, BalancePolicy{
+		Strategy: BalanceWeighted,
+		Weights:  []int{3, 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 8; i++ {
+		if _, err := lb.EmbedSingle(context.Background(), "1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if heavy.callCount() != 6 || light.callCount() != 2 {
+		t.Errorf("Expected 6/2 split, got %d/%d", heavy.callCount(), light.callCount())
+	}
+}
+
+func TestLoadBalancedEmbedderLeastInFlight(t *testing.T) {
+	busy := &taggedEmbedder{id: 0, started: make(chan struct{}), release: make(chan struct{})}
+	idle := &taggedEmbedder{id: 1}
+	lb, err := NewLoadBalancedEmbedder([]Embedder{busy, idle}, BalancePolicy{Strategy: BalanceLeastInFlight})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := lb.EmbedSingle(context.Background(), "1")
+		done <- err
+	}()
+	select {
+	case <-busy.started:
+	case <-time.After(time.Second):
+		t.Fatal("Expected first request to go to the first backend")
+	}
+	if lb.InFlight(0) != 1 {
+		t.Errorf("Expected 1 request in flight, got %d", lb.InFlight(0))
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := lb.EmbedSingle(context.Background(), "1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if idle.callCount() != 4 {
+		t.Errorf("Expected all requests to avoid the busy backend, got %d", idle.callCount())
+	}
+
+	close(busy.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadBalancedEmbedderSkipsFailedBackends(t *testing.T) {
+	healthy := &backendEmbedder{dimension: 3}
+	down := &backendEmbedder{dimension: 3}
+	down.set(&ProviderError{Provider: "mock", Index: -1, Kind: ErrUnavailable}, nil)
+	breaker := NewCircuitBreaker(down, CircuitPolicy{ConsecutiveFailures: 1, Cooldown: time.Hour})
+
+	for _, strategy := range []BalanceStrategy{BalanceRoundRobin, BalanceLeastInFlight, BalanceWeighted} {
+		lb, err := NewLoadBalancedEmbedder([]Embedder{breaker, healthy}, BalancePolicy{Strategy: strategy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 失败的分片换用健康后端，熔断后不再选择故障后端
+		for i := 0; i < 3; i++ {
+			embeddings, err := lb.Embed(context.Background(), numbers(4))
+			if err != nil || len(embeddings) != 4 {
+				t.Fatalf("%s: expected request to succeed on the healthy backend, got %v", strategy, err)
+			}
+			if _, err := lb.EmbedSingle(context.Background(), "a"); err != nil {
+				t.Fatalf("%s: EmbedSingle failed: %v", strategy, err)
+			}
+		}
+	}
+	if down.callCount() != 1 {
+		t.Errorf("Expected open circuit to be skipped, got %d calls to the failed backend", down.callCount())
+	}
+
+	// 所有后端都不可用时返回错误
+	healthy.set(ErrUnavailable, nil)
+	lb, _ := NewLoadBalancedEmbedder([]Embedder{breaker, healthy}, BalancePolicy{})
+	if _, err := lb.EmbedSingle(context.Background(), "a"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable when every backend fails, got %v", err)
+	}
+}
+
+func TestLoadBalancedEmbedderChecksRuntimeDimension(t *testing.T) {
+	lb, err := NewLoadBalancedEmbedder([]Embedder{&backendEmbedder{}, &lazyEmbedder{sizedEmbedder{dimension: 4}}}, BalancePolicy{})
+	if err != nil {
+		t.Fatalf("Lazy backends should pass construction, got %v", err)
+	}
+
+	// 两个分片分别由 3 维和 4 维的后端处理
+	if _, err := lb.Embed(context.Background(), numbers(2)); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("Expected ErrInvalidResponse for mismatched dimensions, got %v", err)
+	}
+}
+
+func TestFactoryExpandsBaseURLs(t *testing.T) {
+	var data = `
+provider: lb-mock
+model: mock-model
+base_urls: ["http://gpu-0:11434", "http://gpu-1:11434"]
+balance:
+  strategy: weighted
+  weights: [2, 1]
+`
+	var config Config
+	if err := yaml.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	created := make(map[string]*taggedEmbedder)
+	factory := NewFactory()
+	factory.RegisterProvider("lb-mock", func(config Config) (Embedder, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		e := &taggedEmbedder{id: float32(len(created))}
+		created[config.BaseURL] = e
+		return e, nil
+	})
+
+	e, err := factory.CreateWithConfig(config)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if _, ok := e.(*LoadBalancedEmbedder); !ok {
+		t.Fatalf("Expected *LoadBalancedEmbedder, got %T", e)
+	}
+	if len(created) != 2 || created["http://gpu-0:11434"] == nil || created["http://gpu-1:11434"] == nil {
+		t.Fatalf("Expected one backend per base URL, got %v", created)
+	}
+
+	if _, err := e.BatchEmbed(context.Background(), numbers(6), 1); err != nil {
+		t.Fatal(err)
+	}
+	if created["http://gpu-0:11434"].callCount() != 4 || created["http://gpu-1:11434"].callCount() != 2 {
+		t.Errorf("Expected 4/2 split by weight, got %d/%d",
+			created["http://gpu-0:11434"].callCount(), created["http://gpu-1:11434"].callCount())
+	}
+
+	for _, policy := range []BalancePolicy{
+		{Strategy: "random"},
+		{Strategy: BalanceWeighted, Weights: []int{1}},
+		{Strategy: BalanceWeighted, Weights: []int{1, 0}},
+	} {
+		config.Balance = policy
+		if _, err := factory.CreateWithConfig(config); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("Policy %+v: expected ErrInvalidConfig, got %v", policy, err)
+		}
+	}
+}
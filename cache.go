@@ -0,0 +1,296 @@
+package embedder
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// CacheStore 嵌入向量缓存存储接口
+// 实现需要并发安全，返回的向量调用方不会修改
+type CacheStore interface {
+	// Get 读取缓存的向量
+	Get(key string) ([]float32, bool)
+
+	// Put 写入向量
+	Put(key string, vector []float32) error
+
+	// Close 释放存储资源
+	Close() error
+}
+
+// CacheEmbedder 为任意 Embedder 增加缓存的装饰器
+// 缓存键由 (provider, model, 维度, 规范化文本哈希, 查询/文档模式) 组成，只有未命中的文本会发送给内部服务
+// 内部服务维度未知（返回 0）时不读取缓存，结果按向量长度作为维度写入
+type CacheEmbedder struct {
+	inner    Embedder
+	store    CacheStore
+	provider string
+	logger   *Logger
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// NewCacheEmbedder 使用缓存存储包装嵌入服务
+// provider 参与缓存键计算，避免不同服务的同名模型互相污染
+func NewCacheEmbedder(inner Embedder, store CacheStore, provider string) *CacheEmbedder {
+	return &CacheEmbedder{
+		inner:    inner,
+		store:    store,
+		provider: provider,
+		logger:   NewLogger("embedding-cache"),
+	}
+}
+
+// 缓存键中的嵌入模式，查询与文档向量分开缓存
+const (
+	cacheModePlain    = ""
+	cacheModeQuery    = "query"
+	cacheModeDocument = "document"
+)
+
+// Embed 批量嵌入多个文本，只请求未命中缓存的部分
+func (c *CacheEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return c.embedMisses(texts, cacheModePlain, func(misses []string) ([][]float32, error) {
+		return c.inner.Embed(ctx, misses)
+	})
+}
+
+// EmbedSingle 嵌入单个文本
+func (c *CacheEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	return c.embedOne(text, cacheModePlain, func() ([]float32, error) {
+		return c.inner.EmbedSingle(ctx, text)
+	})
+}
+
+// BatchEmbed 分批处理大量文本，未命中的文本合并后交给内部服务分批
+func (c *CacheEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	return c.embedMisses(texts, cacheModePlain, func(misses []string) ([][]float32, error) {
+		return c.inner.BatchEmbed(ctx, misses, batchSize)
+	})
+}
+
+// EmbedQuery 以查询模式嵌入文本，内部服务不支持时直接嵌入
+func (c *CacheEmbedder) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	return c.embedOne(query, cacheModeQuery, func() ([]float32, error) {
+		return EmbedQuery(ctx, c.inner, query)
+	})
+}
+
+// EmbedDocuments 以文档模式嵌入多个文本，内部服务不支持时直接嵌入
+func (c *CacheEmbedder) EmbedDocuments(ctx context.Context, documents []string) ([][]float32, error) {
+	return c.embedMisses(documents, cacheModeDocument, func(misses []string) ([][]float32, error) {
+		return EmbedDocuments(ctx, c.inner, misses)
+	})
+}
+
+// GetDimension 获取嵌入维度
+func (c *CacheEmbedder) GetDimension() int {
+	return c.inner.GetDimension()
+}
+
+// GetModel 获取模型名称
+func (c *CacheEmbedder) GetModel() string {
+	return c.inner.GetModel()
+}
+
+// Health 健康检查
+func (c *CacheEmbedder) Health(ctx context.Context) error {
+	return c.inner.Health(ctx)
+}
+
+// Stats 返回缓存命中与未命中次数
+func (c *CacheEmbedder) Stats() (hits, misses int64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// Close 关闭缓存存储
+func (c *CacheEmbedder) Close() error {
+	return c.store.Close()
+}
+
+// embedOne 查询单个文本的缓存，未命中时调用 embed 并写入（私有方法）
+func (c *CacheEmbedder) embedOne(text, mode string, embed func() ([]float32, error)) ([]float32, error) {
+	dimension := c.inner.GetDimension()
+	if dimension > 0 {
+		if vector, ok := c.store.Get(c.key(text, mode, dimension)); ok {
+			c.hits.Add(1)
+			return vector, nil
+		}
+	}
+	c.misses.Add(1)
+
+	vector, err := embed()
+	if err != nil {
+		return nil, err
+	}
+	c.put(c.key(text, mode, keyDimension(dimension, vector)), vector)
+	return vector, nil
+}
+
+// embedMisses 查询缓存，将未命中的文本（去重后）交给 embed，并按原顺序合并结果（私有方法）
+func (c *CacheEmbedder) embedMisses(texts []string, mode string, embed func(misses []string) ([][]float32, error)) ([][]float32, error) {
+	result := make([][]float32, len(texts))
+	keys := make([]string, len(texts))
+
+	var missTexts []string
+	missIndex := make(map[string]int)
+	dimension := c.inner.GetDimension()
+	for i, text := range texts {
+		keys[i] = c.key(text, mode, dimension)
+		if dimension > 0 {
+			if vector, ok := c.store.Get(keys[i]); ok {
+				result[i] = vector
+				continue
+			}
+		}
+		if _, ok := missIndex[keys[i]]; !ok {
+			missIndex[keys[i]] = len(missTexts)
+			missTexts = append(missTexts, text)
+		}
+	}
+
+	c.hits.Add(int64(len(texts) - len(missTexts)))
+	c.misses.Add(int64(len(missTexts)))
+	if len(missTexts) == 0 {
+		return result, nil
+	}
+
+	c.logger.Debug("缓存未命中", Int("total", len(texts)), Int("misses", len(missTexts)))
+
+	embeddings, err := embed(missTexts)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != len(missTexts) {
+		return nil, fmt.Errorf("%w: expected %d embeddings, got %d", ErrInvalidResponse, len(missTexts), len(embeddings))
+	}
+
+	for i := range texts {
+		if result[i] != nil {
+			continue
+		}
+		result[i] = embeddings[missIndex[keys[i]]]
+	}
+	for i, text := range missTexts {
+		c.put(c.key(text, mode, keyDimension(dimension, embeddings[i])), embeddings[i])
+	}
+
+	return result, nil
+}
+
+// put 写入缓存，失败只记录日志不影响嵌入结果（私有方法）
+func (c *CacheEmbedder) put(key string, vector []float32) {
+	if err := c.store.Put(key, vector); err != nil {
+		c.logger.Warn("写入缓存失败", Error(err))
+	}
+}
+
+// key 计算缓存键，维度参与计算，同一模型不同维度设置的向量互不混用（私有方法）
+func (c *CacheEmbedder) key(text, mode string, dimension int) string {
+	h := sha256.New()
+	h.Write([]byte(c.provider))
+	h.Write([]byte{0})
+	h.Write([]byte(c.inner.GetModel()))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.Itoa(dimension)))
+	h.Write([]byte{0})
+	h.Write([]byte(normalizeCacheText(text)))
+	if mode != cacheModePlain {
+		h.Write([]byte{0})
+		h.Write([]byte(mode))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// keyDimension 写入缓存时使用的维度，内部服务维度未知时取向量长度
+func keyDimension(dimension int, vector []float32) int {
+	if dimension > 0 {
+		return dimension
+	}
+	return len(vector)
+}
+
+// normalizeCacheText 规范化文本：去除首尾空白并合并连续空白
+func normalizeCacheText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// MemoryCache 内存LRU缓存
+type MemoryCache struct {
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+	mu       sync.Mutex
+}
+
+// memoryCacheEntry LRU链表节点
+type memoryCacheEntry struct {
+	key    string
+	vector []float32
+}
+
+// NewMemoryCache 创建内存LRU缓存，capacity <= 0 表示不限容量
+func NewMemoryCache(capacity int) *MemoryCache {
+	return &MemoryCache{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Get 读取缓存的向量
+func (m *MemoryCache) Get(key string) ([]float32, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	elem, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	m.order.MoveToFront(elem)
+	return elem.Value.(*memoryCacheEntry).vector, true
+}
+
+// Put 写入向量，超出容量时淘汰最久未使用的条目
+func (m *MemoryCache) Put(key string, vector []float32) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if elem, ok := m.items[key]; ok {
+		elem.Value.(*memoryCacheEntry).vector = vector
+		m.order.MoveToFront(elem)
+		return nil
+	}
+
+	m.items[key] = m.order.PushFront(&memoryCacheEntry{key: key, vector: vector})
+	if m.capacity > 0 && m.order.Len() > m.capacity {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.items, oldest.Value.(*memoryCacheEntry).key)
+	}
+	return nil
+}
+
+// Len 返回缓存条目数
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+// Close 清空缓存
+func (m *MemoryCache) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items = make(map[string]*list.Element)
+	m.order.Init()
+	return nil
+}
@@ -0,0 +1,199 @@
+package embedder
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sync"
+)
+
+// fileCacheMagic 缓存文件头，用于识别文件格式
+const fileCacheMagic = "EMBCACHE1\n"
+
+// maxFileCacheKeyLen 单条记录键的最大长度，用于识别损坏的记录
+const maxFileCacheKeyLen = 1 << 16
+
+// FileCache 单文件追加日志形式的磁盘缓存，进程重启后依然有效
+// 记录格式：keyLen(uint32) key dim(uint32) dim*float32，均为小端序
+// 打开时重放日志建立 key -> 偏移量 索引，向量按需从磁盘读取
+type FileCache struct {
+	file   *os.File
+	index  map[string]fileCacheEntry
+	offset int64
+	mu     sync.RWMutex
+}
+
+// fileCacheEntry 向量在文件中的位置
+type fileCacheEntry struct {
+	offset int64
+	dim    int
+}
+
+// OpenFileCache 打开或创建磁盘缓存文件
+// 文件末尾不完整的记录（如写入时进程崩溃）会被截断丢弃
+func OpenFileCache(path string) (*FileCache, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &FileCache{
+		file:  file,
+		index: make(map[string]fileCacheEntry),
+	}
+	if err := cache.load(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to load cache file %s: %w", path, err)
+	}
+	return cache, nil
+}
+
+// Get 读取缓存的向量
+func (f *FileCache) Get(key string) ([]float32, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	entry, ok := f.index[key]
+	if !ok || f.file == nil {
+		return nil, false
+	}
+
+	buf := make([]byte, 4*entry.dim)
+	if _, err := f.file.ReadAt(buf, entry.offset); err != nil {
+		return nil, false
+	}
+	return decodeFloat32s(buf), true
+}
+
+// Put 追加写入向量，已存在的键直接跳过
+func (f *FileCache) Put(key string, vector []float32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	if _, ok := f.index[key]; ok {
+		return nil
+	}
+
+	record := make([]byte, 0, 8+len(key)+4*len(vector))
+	record = binary.LittleEndian.AppendUint32(record, uint32(len(key)))
+	record = append(record, key...)
+	record = binary.LittleEndian.AppendUint32(record, uint32(len(vector)))
+	for _, v := range vector {
+		record = binary.LittleEndian.AppendUint32(record, math.Float32bits(v))
+	}
+
+	if _, err := f.file.WriteAt(record, f.offset); err != nil {
+		return err
+	}
+
+	f.index[key] = fileCacheEntry{offset: f.offset + int64(8+len(key)), dim: len(vector)}
+	f.offset += int64(len(record))
+	return nil
+}
+
+// Len 返回缓存条目数
+func (f *FileCache) Len() int {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.index)
+}
+
+// Close 同步并关闭缓存文件
+func (f *FileCache) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Sync()
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	f.file = nil
+	return err
+}
+
+// load 重放日志建立索引（私有方法）
+func (f *FileCache) load() error {
+	info, err := f.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() == 0 {
+		if _, err := f.file.WriteAt([]byte(fileCacheMagic), 0); err != nil {
+			return err
+		}
+		f.offset = int64(len(fileCacheMagic))
+		return nil
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(f.file, 0, info.Size()))
+	magic := make([]byte, len(fileCacheMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != fileCacheMagic {
+		return errors.New("not an embedding cache file")
+	}
+
+	offset := int64(len(fileCacheMagic))
+	header := make([]byte, 4)
+	for {
+		entry, key, size, err := readFileCacheRecord(reader, header, offset)
+		if err != nil {
+			break
+		}
+		f.index[key] = entry
+		offset += size
+	}
+
+	// 丢弃末尾不完整的记录，后续从最后一条完整记录之后追加
+	if offset < info.Size() {
+		if err := f.file.Truncate(offset); err != nil {
+			return err
+		}
+	}
+	f.offset = offset
+	return nil
+}
+
+// readFileCacheRecord 读取一条记录，返回向量位置、键和记录总长度
+func readFileCacheRecord(reader *bufio.Reader, header []byte, offset int64) (fileCacheEntry, string, int64, error) {
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return fileCacheEntry{}, "", 0, err
+	}
+	keyLen := int(binary.LittleEndian.Uint32(header))
+	if keyLen == 0 || keyLen > maxFileCacheKeyLen {
+		return fileCacheEntry{}, "", 0, errors.New("invalid key length")
+	}
+
+	key := make([]byte, keyLen)
+	if _, err := io.ReadFull(reader, key); err != nil {
+		return fileCacheEntry{}, "", 0, err
+	}
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return fileCacheEntry{}, "", 0, err
+	}
+	dim := int(binary.LittleEndian.Uint32(header))
+	if _, err := reader.Discard(4 * dim); err != nil {
+		return fileCacheEntry{}, "", 0, err
+	}
+
+	entry := fileCacheEntry{offset: offset + int64(8+keyLen), dim: dim}
+	return entry, string(key), int64(8 + keyLen + 4*dim), nil
+}
+
+// decodeFloat32s 解析小端float32数组
+func decodeFloat32s(data []byte) []float32 {
+	result := make([]float32, len(data)/4)
+	for i := range result {
+		result[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+	return result
+}
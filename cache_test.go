@@ -0,0 +1,208 @@
+package embedder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// countingEmbedder 记录收到的文本，向量首元素为文本长度
+type countingEmbedder struct {
+	MockEmbedder
+	received []string
+}
+
+func (c *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	c.received = append(c.received, texts...)
+	result := make([][]float32, len(texts))
+	for i, text := range texts {
+		result[i] = []float32{float32(len(text)), 0.5}
+	}
+	return result, nil
+}
+
+func (c *countingEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := c.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (c *countingEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	return c.Embed(ctx, texts)
+}
+
+func TestCacheEmbedderOnlyEmbedsMisses(t *testing.T) {
+	inner := &countingEmbedder{}
+	embedder := NewCacheEmbedder(inner, NewMemoryCache(0), "mock")
+	ctx := context.Background()
+
+	if _, err := embedder.Embed(ctx, []string{"a", "bb"}); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+
+	texts := []string{"ccc", " a ", "bb", "ccc", "dddd"}
+	embeddings, err := embedder.BatchEmbed(ctx, texts, 2)
+	if err != nil {
+		t.Fatalf("BatchEmbed failed: %v", err)
+	}
+
+	// "a" 规范化后命中缓存，重复的 "ccc" 只请求一次
+	want := []string{"a", "bb", "ccc", "dddd"}
+	if len(inner.received) != len(want) {
+		t.Fatalf("Expected inner to receive %v, got %v", want, inner.received)
+	}
+	for i := range want {
+		if inner.received[i] != want[i] {
+			t.Errorf("Expected inner to receive %v, got %v", want, inner.received)
+			break
+		}
+	}
+
+	expected := []float32{3, 1, 2, 3, 4}
+	for i, embedding := range embeddings {
+		if embedding[0] != expected[i] {
+			t.Errorf("Embedding %d out of order: got %v", i, embedding)
+		}
+	}
+
+	hits, misses := embedder.Stats()
+	if hits != 3 || misses != 4 {
+		t.Errorf("Expected 3 hits and 4 misses, got %d and %d", hits, misses)
+	}
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	cache := NewMemoryCache(2)
+	cache.Put("a", []float32{1})
+	cache.Put("b", []float32{2})
+	cache.Get("a")
+	cache.Put("c", []float32{3})
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("Expected least recently used entry to be evicted")
+	}
+	if _, ok := cache.Get("a"); !ok {
+		t.Error("Expected recently used entry to be kept")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", cache.Len())
+	}
+}
+
+func TestFileCachePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "embeddings.cache")
+
+	cache, err := OpenFileCache(path)
+	if err != nil {
+		t.Fatalf("OpenFileCache failed: %v", err)
+	}
+	if err := cache.Put("a", []float32{1, 2, 3}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := cache.Put("b", []float32{4, 5}); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// 模拟写入中途崩溃留下的不完整记录
+	file, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	file.Write([]byte{1, 0, 0, 0, 'c'})
+	file.Close()
+
+	cache, err = OpenFileCache(path)
+	if err != nil {
+		t.Fatalf("Reopen failed: %v", err)
+	}
+	defer cache.Close()
+
+	if cache.Len() != 2 {
+		t.Errorf("Expected 2 entries after reopen, got %d", cache.Len())
+	}
+	vector, ok := cache.Get("a")
+	if !ok || len(vector) != 3 || vector[2] != 3 {
+		t.Errorf("Unexpected vector for a: %v", vector)
+	}
+
+	if err := cache.Put("c", []float32{6}); err != nil {
+		t.Fatalf("Put after reopen failed: %v", err)
+	}
+	if vector, ok := cache.Get("c"); !ok || vector[0] != 6 {
+		t.Errorf("Unexpected vector for c: %v", vector)
+	}
+	if vector, ok := cache.Get("b"); !ok || vector[1] != 5 {
+		t.Errorf("Unexpected vector for b: %v", vector)
+	}
+}
+
+// sizedEmbedder 按 dimension 输出向量的测试嵌入服务，模拟同一模型的不同维度设置
+type sizedEmbedder struct {
+	MockEmbedder
+	dimension int
+	calls     int
+}
+
+func (s *sizedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	s.calls++
+	result := make([][]float32, len(texts))
+	for i := range texts {
+		result[i] = make([]float32, s.dimension)
+	}
+	return result, nil
+}
+
+func (s *sizedEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	embeddings, _ := s.Embed(ctx, []string{text})
+	return embeddings[0], nil
+}
+
+func (s *sizedEmbedder) GetDimension() int {
+	return s.dimension
+}
+
+func TestCacheKeyIncludesDimension(t *testing.T) {
+	store, err := OpenFileCache(filepath.Join(t.TempDir(), "embeddings.cache"))
+	if err != nil {
+		t.Fatalf("OpenFileCache failed: %v", err)
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	small := &sizedEmbedder{dimension: 2}
+	large := &sizedEmbedder{dimension: 4}
+	if _, err := NewCacheEmbedder(small, store, "mock").Embed(ctx, []string{"x"}); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+
+	vector, err := NewCacheEmbedder(large, store, "mock").EmbedSingle(ctx, "x")
+	if err != nil {
+		t.Fatalf("EmbedSingle failed: %v", err)
+	}
+	if len(vector) != 4 || large.calls != 1 {
+		t.Errorf("Expected a 4-dimensional miss, got %d dimensions after %d calls", len(vector), large.calls)
+	}
+
+	again := &sizedEmbedder{dimension: 2}
+	if vector, _ := NewCacheEmbedder(again, store, "mock").EmbedSingle(ctx, "x"); len(vector) != 2 || again.calls != 0 {
+		t.Errorf("Expected a 2-dimensional hit, got %d dimensions after %d calls", len(vector), again.calls)
+	}
+
+	// 维度未知时不读取缓存
+	unknown := &sizedEmbedder{}
+	if _, err := NewCacheEmbedder(unknown, store, "mock").Embed(ctx, []string{"x"}); err != nil || unknown.calls != 1 {
+		t.Errorf("Expected a miss for unknown dimension, got %v after %d calls", err, unknown.calls)
+	}
+}
+
+func TestOpenFileCacheRejectsForeignFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "other.txt")
+	os.WriteFile(path, []byte("not a cache"), 0o644)
+
+	if _, err := OpenFileCache(path); err == nil {
+		t.Error("Expected error for foreign file")
+	}
+}
@@ -0,0 +1,449 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 熔断期间请求被直接拒绝，可通过 errors.Is 判断
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitState 熔断状态
+type CircuitState int
+
+const (
+	// CircuitClosed 正常放行请求
+	CircuitClosed CircuitState = iota
+	// CircuitOpen 熔断中，冷却期内拒绝请求
+	CircuitOpen
+	// CircuitHalfOpen 冷却期结束，放行少量试探请求
+	CircuitHalfOpen
+)
+
+// String 返回状态名称
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("CircuitState(%d)", int(s))
+	}
+}
+
+// CircuitPolicy 熔断策略，零值字段使用 DefaultCircuitPolicy 中的值
+// ConsecutiveFailures 和 FailureRatio 都为 0 时按连续失败次数熔断
+type CircuitPolicy struct {
+	// ConsecutiveFailures 连续失败多少次后熔断
+	ConsecutiveFailures int `yaml:"consecutive_failures"`
+	// FailureRatio 统计窗口内失败比例达到该值时熔断，0 表示不按比例判断
+	FailureRatio float64 `yaml:"failure_ratio"`
+	// MinRequests 窗口内请求数达到该值后才按比例判断
+	MinRequests int `yaml:"min_requests"`
+	// Window 失败比例的统计窗口
+	Window time.Duration `yaml:"window"`
+	// Cooldown 熔断后多久进入半开状态
+	Cooldown time.Duration `yaml:"cooldown"`
+	// HalfOpenRequests 半开状态放行的试探请求数，全部成功后恢复，任一失败则重新熔断
+	HalfOpenRequests int `yaml:"half_open_requests"`
+
+	// OnStateChange 状态变化时的回调，用于告警；在锁外调用
+	OnStateChange func(from, to CircuitState) `yaml:"-"`
+}
+
+// DefaultCircuitPolicy 默认熔断策略
+var DefaultCircuitPolicy = CircuitPolicy{
+	ConsecutiveFailures: 5,
+	MinRequests:         10,
+	Window:              time.Minute,
+	Cooldown:            30 * time.Second,
+	HalfOpenRequests:    1,
+}
+
+// Enabled 是否设置了熔断条件
+func (p CircuitPolicy) Enabled() bool {
+	return p.ConsecutiveFailures > 0 || p.FailureRatio > 0
+}
+
+// Validate 检查策略配置
+func (p CircuitPolicy) Validate() error {
+	if p.FailureRatio < 0 || p.FailureRatio > 1 {
+		return fmt.Errorf("%w: failure_ratio must be between 0 and 1", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// withDefaults 补全零值字段（私有方法）
+func (p CircuitPolicy) withDefaults() CircuitPolicy {
+	if !p.Enabled() {
+		p.ConsecutiveFailures = DefaultCircuitPolicy.ConsecutiveFailures
+	}
+	if p.MinRequests <= 0 {
+		p.MinRequests = DefaultCircuitPolicy.MinRequests
+	}
+	if p.Window <= 0 {
+		p.Window = DefaultCircuitPolicy.Window
+	}
+	if p.Cooldown <= 0 {
+		p.Cooldown = DefaultCircuitPolicy.Cooldown
+	}
+	if p.HalfOpenRequests <= 0 {
+		p.HalfOpenRequests = DefaultCircuitPolicy.HalfOpenRequests
+	}
+	return p
+}
+
+// CircuitOpenError 熔断期间拒绝请求的错误
+// errors.Is 同时匹配 ErrCircuitOpen 和 ErrUnavailable，故障转移会跳过该后端
+type CircuitOpenError struct {
+	// Until 预计进入半开状态的时间，半开试探名额已满时为零值
+	Until time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	if wait := e.RetryAfter(); wait > 0 {
+		return fmt.Sprintf("%s, retry in %s", ErrCircuitOpen, wait.Round(time.Millisecond))
+	}
+	return ErrCircuitOpen.Error()
+}
+
+// Unwrap 暴露 ErrCircuitOpen 和 ErrUnavailable
+func (e *CircuitOpenError) Unwrap() []error {
+	return []error{ErrCircuitOpen, ErrUnavailable}
+}
+
+// RetryAfter 返回距离进入半开状态的时间
+func (e *CircuitOpenError) RetryAfter() time.Duration {
+	if e.Until.IsZero() {
+		return 0
+	}
+	if wait := time.Until(e.Until); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// CircuitBreaker 熔断装饰器：后端持续失败时直接拒绝请求，避免每次都等到超时
+type CircuitBreaker struct {
+	inner   Embedder
+	circuit *circuit
+	logger  *Logger
+}
+
+// NewCircuitBreaker 使用熔断策略包装嵌入服务
+func NewCircuitBreaker(inner Embedder, policy CircuitPolicy) *CircuitBreaker {
+	b := &CircuitBreaker{inner: inner, logger: NewLogger("circuit-breaker")}
+	b.circuit = newCircuit(policy, func(from, to CircuitState, err error) {
+		fields := []Field{String("model", inner.GetModel()), String("from", from.String()), String("to", to.String())}
+		if err != nil {
+			fields = append(fields, Error(err))
+		}
+		if to == CircuitOpen {
+			b.logger.Warn("熔断器已打开", fields...)
+		} else {
+			b.logger.Info("熔断器状态变化", fields...)
+		}
+		if policy.OnStateChange != nil {
+			policy.OnStateChange(from, to)
+		}
+	})
+	return b
+}
+
+// Embed 批量嵌入多个文本
+func (b *CircuitBreaker) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var result [][]float32
+	err := b.do(ctx, func() error {
+		var err error
+		result, err = b.inner.Embed(ctx, texts)
+		return err
+	})
+	return result, err
+}
+
+// EmbedSingle 嵌入单个文本
+func (b *CircuitBreaker) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	var result []float32
+	err := b.do(ctx, func() error {
+		var err error
+		result, err = b.inner.EmbedSingle(ctx, text)
+		return err
+	})
+	return result, err
+}
+
+// BatchEmbed 分批处理大量文本，整体作为一次请求计入熔断统计
+func (b *CircuitBreaker) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	var result [][]float32
+	err := b.do(ctx, func() error {
+		var err error
+		result, err = b.inner.BatchEmbed(ctx, texts, batchSize)
+		return err
+	})
+	return result, err
+}
+
+// EmbedQuery 以查询模式嵌入文本，内部服务不支持时直接嵌入
+func (b *CircuitBreaker) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	var result []float32
+	err := b.do(ctx, func() error {
+		var err error
+		result, err = EmbedQuery(ctx, b.inner, query)
+		return err
+	})
+	return result, err
+}
+
+// EmbedDocuments 以文档模式嵌入多个文本，内部服务不支持时直接嵌入
+func (b *CircuitBreaker) EmbedDocuments(ctx context.Context, documents []string) ([][]float32, error) {
+	var result [][]float32
+	err := b.do(ctx, func() error {
+		var err error
+		result, err = EmbedDocuments(ctx, b.inner, documents)
+		return err
+	})
+	return result, err
+}
+
+// GetDimension 获取嵌入维度
+func (b *CircuitBreaker) GetDimension() int {
+	return b.inner.GetDimension()
+}
+
+// GetModel 获取模型名称
+func (b *CircuitBreaker) GetModel() string {
+	return b.inner.GetModel()
+}
+
+// Health 健康检查，熔断期间直接返回 CircuitOpenError，半开时可作为试探请求
+func (b *CircuitBreaker) Health(ctx context.Context) error {
+	return b.do(ctx, func() error {
+		return b.inner.Health(ctx)
+	})
+}
+
+// State 返回当前熔断状态
+func (b *CircuitBreaker) State() CircuitState {
+	return b.circuit.State()
+}
+
+// available 当前是否可能放行请求（未熔断或冷却期已过），不改变状态（私有方法）
+func (b *CircuitBreaker) available() bool {
+	return b.circuit.available()
+}
+
+// do 经熔断器放行后执行 fn 并记录结果（私有方法）
+func (b *CircuitBreaker) do(ctx context.Context, fn func() error) error {
+	ticket, err := b.circuit.acquire()
+	if err != nil {
+		return err
+	}
+	err = fn()
+	b.circuit.done(ctx, ticket, err)
+	return err
+}
+
+// circuit 熔断状态机，CircuitBreaker 和 FailoverEmbedder 共用
+type circuit struct {
+	policy CircuitPolicy
+	notify func(from, to CircuitState, err error)
+
+	mu sync.Mutex
+	// generation 每次状态变化加一，状态变化前发出的请求结果不再计入
+	generation  uint64
+	state       CircuitState
+	consecutive int
+	requests    int
+	failures    int
+	windowStart time.Time
+	openedAt    time.Time
+	// 半开状态下进行中和已成功的试探请求数
+	trials    int
+	successes int
+}
+
+// newCircuit 创建熔断状态机，notify 在状态变化后于锁外调用
+func newCircuit(policy CircuitPolicy, notify func(from, to CircuitState, err error)) *circuit {
+	return &circuit{
+		policy:      policy.withDefaults(),
+		notify:      notify,
+		windowStart: time.Now(),
+	}
+}
+
+// State 返回当前状态，冷却期已过但尚未有请求时仍为 CircuitOpen
+func (c *circuit) State() CircuitState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state
+}
+
+// available 是否未熔断或冷却期已过（私有方法）
+func (c *circuit) available() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state != CircuitOpen || time.Since(c.openedAt) >= c.policy.Cooldown
+}
+
+// acquire 申请放行一个请求，返回用于 done 的凭据；拒绝时返回 CircuitOpenError
+func (c *circuit) acquire() (uint64, error) {
+	c.mu.Lock()
+	from := c.state
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= c.policy.Cooldown {
+		c.setState(CircuitHalfOpen)
+	}
+
+	var err error
+	switch c.state {
+	case CircuitOpen:
+		err = &CircuitOpenError{Until: c.openedAt.Add(c.policy.Cooldown)}
+	case CircuitHalfOpen:
+		if c.trials+c.successes >= c.policy.HalfOpenRequests {
+			err = &CircuitOpenError{}
+		} else {
+			c.trials++
+		}
+	}
+	ticket, to := c.generation, c.state
+	c.mu.Unlock()
+
+	c.changed(from, to, nil)
+	return ticket, err
+}
+
+// done 记录请求结果：调用方取消不计入，与后端无关的错误视为成功
+func (c *circuit) done(ctx context.Context, ticket uint64, err error) {
+	switch {
+	case err == nil || !backendFailure(err):
+		c.record(ticket, nil)
+	case ctx.Err() != nil:
+		// 调用方取消或超时不能说明后端故障
+		c.release(ticket)
+	default:
+		c.record(ticket, err)
+	}
+}
+
+// trip 立即熔断，用于健康检查失败
+func (c *circuit) trip(err error) {
+	c.mu.Lock()
+	from := c.state
+	c.open()
+	c.mu.Unlock()
+
+	c.changed(from, CircuitOpen, err)
+}
+
+// reset 立即恢复为关闭状态，用于健康检查通过
+func (c *circuit) reset() {
+	c.mu.Lock()
+	from := c.state
+	c.setState(CircuitClosed)
+	c.mu.Unlock()
+
+	c.changed(from, CircuitClosed, nil)
+}
+
+// record 按结果更新计数并判断是否需要切换状态（私有方法）
+func (c *circuit) record(ticket uint64, err error) {
+	c.mu.Lock()
+	if ticket != c.generation {
+		c.mu.Unlock()
+		return
+	}
+	from := c.state
+
+	switch c.state {
+	case CircuitHalfOpen:
+		c.trials--
+		if err != nil {
+			c.open()
+		} else if c.successes++; c.successes >= c.policy.HalfOpenRequests {
+			c.setState(CircuitClosed)
+		}
+	case CircuitClosed:
+		if time.Since(c.windowStart) >= c.policy.Window {
+			c.requests, c.failures = 0, 0
+			c.windowStart = time.Now()
+		}
+		c.requests++
+		if err == nil {
+			c.consecutive = 0
+		} else {
+			c.consecutive++
+			c.failures++
+			if c.shouldTrip() {
+				c.open()
+			}
+		}
+	}
+	to := c.state
+	c.mu.Unlock()
+
+	c.changed(from, to, err)
+}
+
+// release 没有结论的请求归还半开试探名额（私有方法）
+func (c *circuit) release(ticket uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ticket == c.generation && c.state == CircuitHalfOpen {
+		c.trials--
+	}
+}
+
+// shouldTrip 关闭状态下是否达到熔断条件，调用方需持有锁（私有方法）
+func (c *circuit) shouldTrip() bool {
+	if c.policy.ConsecutiveFailures > 0 && c.consecutive >= c.policy.ConsecutiveFailures {
+		return true
+	}
+	return c.policy.FailureRatio > 0 && c.requests >= c.policy.MinRequests &&
+		float64(c.failures)/float64(c.requests) >= c.policy.FailureRatio
+}
+
+// open 切换到熔断状态，调用方需持有锁（私有方法）
+func (c *circuit) open() {
+	c.setState(CircuitOpen)
+	c.openedAt = time.Now()
+}
+
+// setState 切换状态并清空计数，调用方需持有锁（私有方法）
+func (c *circuit) setState(state CircuitState) {
+	if c.state == state {
+		return
+	}
+	c.state = state
+	c.generation++
+	c.consecutive, c.requests, c.failures = 0, 0, 0
+	c.trials, c.successes = 0, 0
+	c.windowStart = time.Now()
+}
+
+// changed 状态变化时通知，在锁外调用（私有方法）
+func (c *circuit) changed(from, to CircuitState, err error) {
+	if from != to && c.notify != nil {
+		c.notify(from, to, err)
+	}
+}
+
+// backendFailure 判断错误是否说明后端故障：服务不可用、限流、5xx 及网络错误
+// 超长输入、配置错误、认证失败等其他错误与后端状态无关，不计入熔断
+func backendFailure(err error) bool {
+	if errors.Is(err, ErrUnavailable) || errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	var coder statusCoder
+	if errors.As(err, &coder) && coder.HTTPStatusCode() >= http.StatusInternalServerError {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,165 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerConsecutiveFailures(t *testing.T) {
+	inner := &backendEmbedder{dimension: 3}
+
+	var mu sync.Mutex
+	var transitions []CircuitState
+	breaker := NewCircuitBreaker(inner, CircuitPolicy{
+		ConsecutiveFailures: 3,
+		Cooldown:            20 * time.Millisecond,
+		HalfOpenRequests:    2,
+		OnStateChange: func(from, to CircuitState) {
+			mu.Lock()
+			defer mu.Unlock()
+			transitions = append(transitions, to)
+		},
+	})
+	ctx := context.Background()
+
+	inner.set(&ProviderError{Provider: "mock", Index: -1, Kind: ErrUnavailable}, nil)
+	for i := 0; i < 3; i++ {
+		if _, err := breaker.Embed(ctx, []string{"a"}); !errors.Is(err, ErrUnavailable) || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Attempt %d: expected provider error, got %v", i, err)
+		}
+	}
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("Expected open circuit, got %s", breaker.State())
+	}
+
+	_, err := breaker.EmbedSingle(ctx, "a")
+	var openErr *CircuitOpenError
+	if !errors.As(err, &openErr) || !errors.Is(err, ErrCircuitOpen) || !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Expected CircuitOpenError, got %v", err)
+	}
+	if openErr.RetryAfter() <= 0 || inner.callCount() != 3 {
+		t.Errorf("Expected fail fast with retry hint, got %v after %d calls", openErr.RetryAfter(), inner.callCount())
+	}
+	if DefaultRetryPolicy.Retryable(err) || ErrorKind(err) != "circuit_open" {
+		t.Errorf("Circuit open errors should not be retried and should be classified, got kind %s", ErrorKind(err))
+	}
+
+	// 半开状态需要 2 次试探都成功才恢复
+	inner.set(nil, nil)
+	time.Sleep(30 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if _, err := breaker.Embed(ctx, []string{"a"}); err != nil {
+			t.Fatalf("Trial %d failed: %v", i, err)
+		}
+		if want := []CircuitState{CircuitHalfOpen, CircuitClosed}[i]; breaker.State() != want {
+			t.Errorf("After trial %d: expected %s, got %s", i, want, breaker.State())
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitClosed}
+	if len(transitions) != len(want) {
+		t.Fatalf("Expected transitions %v, got %v", want, transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("Expected transitions %v, got %v", want, transitions)
+		}
+	}
+}
+
+func TestCircuitBreakerFailureRatio(t *testing.T) {
+	inner := &backendEmbedder{dimension: 3}
+	breaker := NewCircuitBreaker(inner, CircuitPolicy{FailureRatio: 0.5, MinRequests: 4, Cooldown: time.Hour})
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		if i%2 == 1 {
+			inner.set(ErrUnavailable, nil)
+		} else {
+			inner.set(nil, nil)
+		}
+		breaker.Embed(ctx, []string{"a"})
+		if want := i == 3; (breaker.State() == CircuitOpen) != want {
+			t.Fatalf("After request %d: unexpected state %s", i, breaker.State())
+		}
+	}
+}
+
+func TestCircuitBreakerIgnoresCallerErrors(t *testing.T) {
+	inner := &backendEmbedder{dimension: 3}
+	breaker := NewCircuitBreaker(inner, CircuitPolicy{ConsecutiveFailures: 1, Cooldown: 20 * time.Millisecond})
+
+	inner.set(&ProviderError{Provider: "mock", Index: 0, Kind: ErrInputTooLong}, nil)
+	breaker.Embed(context.Background(), []string{"a"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	inner.set(context.Canceled, nil)
+	breaker.Embed(ctx, []string{"a"})
+
+	if breaker.State() != CircuitClosed {
+		t.Fatalf("Input errors and cancellation should not trip the circuit, got %s", breaker.State())
+	}
+
+	// 半开试探失败时重新熔断
+	inner.set(ErrUnavailable, ErrUnavailable)
+	breaker.Embed(context.Background(), []string{"a"})
+	time.Sleep(30 * time.Millisecond)
+	breaker.Health(context.Background())
+	if _, err := breaker.Embed(context.Background(), []string{"a"}); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected circuit to reopen after failed trial, got %v", err)
+	}
+}
+
+func TestBackendFailure(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"unavailable", ErrUnavailable, true},
+		{"rate limited", &ProviderError{Kind: ErrRateLimited, StatusCode: http.StatusTooManyRequests, Index: -1}, true},
+		{"status 502", &ProviderError{StatusCode: http.StatusBadGateway, Index: -1}, true},
+		{"network", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"input too long", &ProviderError{Kind: ErrInputTooLong, StatusCode: http.StatusBadRequest, Index: 0}, false},
+		{"unauthorized", &ProviderError{Kind: ErrUnauthorized, StatusCode: http.StatusUnauthorized, Index: -1}, false},
+		{"model not found", &ProviderError{Kind: ErrModelNotFound, StatusCode: http.StatusNotFound, Index: -1}, false},
+		{"invalid response", fmt.Errorf("%w: bad json", ErrInvalidResponse), false},
+		{"unknown", errors.New("boom"), false},
+	}
+	for _, c := range cases {
+		if got := backendFailure(c.err); got != c.want {
+			t.Errorf("%s: backendFailure(%v) = %v, want %v", c.name, c.err, got, c.want)
+		}
+	}
+}
+
+func TestFactoryWrapsCircuitBreaker(t *testing.T) {
+	inner := &backendEmbedder{dimension: 3}
+	factory := NewFactory()
+	factory.RegisterProvider("circuit-mock", func(config Config) (Embedder, error) {
+		return inner, nil
+	})
+
+	config := Config{Provider: "circuit-mock", Circuit: CircuitPolicy{ConsecutiveFailures: 1}}
+	e, err := factory.CreateWithConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e.(*CircuitBreaker); !ok {
+		t.Fatalf("Expected *CircuitBreaker, got %T", e)
+	}
+
+	config.Circuit.FailureRatio = 2
+	if _, err := factory.CreateWithConfig(config); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
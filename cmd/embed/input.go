@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// maxLineSize 单行输入的最大字节数
+const maxLineSize = 16 << 20
+
+// record 一条待嵌入的文本
+type record struct {
+	ID   string
+	Text string
+}
+
+// inputOptions 输入格式配置
+type inputOptions struct {
+	// Format text 表示每行一条文本，jsonl 表示每行一个 JSON 对象
+	Format string
+	// Field jsonl 中文本所在的字段
+	Field string
+	// IDField jsonl 中 id 所在的字段，缺失时使用行号
+	IDField string
+}
+
+// readRecords 依次读取各输入源，"-" 表示标准输入
+// 没有显式 id 时使用行号，多个输入源时加上文件名前缀，保证 --resume 时 id 稳定
+func readRecords(sources []string, stdin io.Reader, opts inputOptions, fn func(record) error) error {
+	for _, source := range sources {
+		prefix := ""
+		if len(sources) > 1 {
+			prefix = source + ":"
+		}
+
+		format := opts.Format
+		if format == "" {
+			format = "text"
+			if strings.HasSuffix(source, ".jsonl") || strings.HasSuffix(source, ".ndjson") {
+				format = "jsonl"
+			}
+		}
+
+		if err := readSource(source, stdin, format, prefix, opts, fn); err != nil {
+			return fmt.Errorf("%s: %w", source, err)
+		}
+	}
+	return nil
+}
+
+// readSource 打开并读取单个输入源
+func readSource(source string, stdin io.Reader, format, prefix string, opts inputOptions, fn func(record) error) error {
+	if source == "-" {
+		return scanRecords(stdin, format, prefix, opts, fn)
+	}
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return scanRecords(file, format, prefix, opts, fn)
+}
+
+// scanRecords 逐行解析一个输入源
+func scanRecords(r io.Reader, format, prefix string, opts inputOptions, fn func(record) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		rec := record{ID: prefix + strconv.Itoa(line), Text: text}
+		if format == "jsonl" {
+			var err error
+			if rec, err = parseJSONLine(text, opts); err != nil {
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+			if rec.ID == "" {
+				rec.ID = prefix + strconv.Itoa(line)
+			}
+		} else if format != "text" {
+			return fmt.Errorf("unknown input format: %s", format)
+		}
+		// id 按行写入 .ids 文件并用于 --resume，不能跨行
+		if strings.ContainsAny(rec.ID, "\r\n") {
+			return fmt.Errorf("line %d: id %q contains a line break", line, rec.ID)
+		}
+
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// parseJSONLine 从 JSON 对象中取出文本和 id
+func parseJSONLine(line string, opts inputOptions) (record, error) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &obj); err != nil {
+		return record{}, err
+	}
+
+	text, ok := obj[opts.Field].(string)
+	if !ok {
+		return record{}, fmt.Errorf("field %q is missing or not a string", opts.Field)
+	}
+
+	var id string
+	switch v := obj[opts.IDField].(type) {
+	case nil:
+	case string:
+		id = v
+	case float64:
+		id = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		id = fmt.Sprint(v)
+	}
+	return record{ID: id, Text: text}, nil
+}
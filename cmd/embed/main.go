@@ -0,0 +1,195 @@
+// embed 命令行嵌入工具，从标准输入、文本文件或 JSONL 读取文本并输出向量
+//
+//	embed -model nomic-embed-text docs.txt > vectors.jsonl
+//	embed -config embedder.yaml -field body -o corpus.npy corpus.jsonl
+//	embed -config embedder.yaml -o corpus.npy --resume corpus.jsonl   # 中断后继续
+//
+// 文本输入每个非空行是一条记录，id 为行号；JSONL 输入从 -field 取文本、从 -id-field 取 id。
+// 输出格式按 -o 的扩展名推断（.jsonl、.csv、.npy、.bin/.f32），也可用 -format 指定；
+// npy 和 raw 输出另写一个 <输出>.ids 文件，每行一个 id，与向量按行对应。
+// id 不能包含换行，也不能重复（-skip-duplicate-ids 时只保留第一条）。
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	embedder "github.com/Kizunad/modular-embedder"
+)
+
+// options 命令行参数
+type options struct {
+	ConfigPath string
+	Provider   string
+	BaseURL    string
+	Model      string
+	Timeout    time.Duration
+	Input      inputOptions
+	Output     string
+	Format     string
+	BatchSize  int
+	Resume     bool
+	// SkipDuplicates 跳过输入中重复的 id，否则遇到重复 id 时报错
+	SkipDuplicates bool
+	Sources        []string
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.ConfigPath, "config", "", "YAML 配置文件，格式同 embedder.LoadConfig")
+	flag.StringVar(&opts.Provider, "provider", "", "提供者，覆盖配置文件")
+	flag.StringVar(&opts.BaseURL, "base-url", "", "服务地址，覆盖配置文件")
+	flag.StringVar(&opts.Model, "model", "", "模型名称，覆盖配置文件")
+	flag.DurationVar(&opts.Timeout, "timeout", 0, "单次请求超时，覆盖配置文件")
+	flag.StringVar(&opts.Input.Format, "input-format", "", "输入格式 text 或 jsonl，默认按扩展名推断")
+	flag.StringVar(&opts.Input.Field, "field", "text", "JSONL 输入中文本所在的字段")
+	flag.StringVar(&opts.Input.IDField, "id-field", "id", "JSONL 输入中 id 所在的字段，缺失时使用行号")
+	flag.StringVar(&opts.Output, "o", "", "输出文件，默认写入标准输出")
+	flag.StringVar(&opts.Format, "format", "", "输出格式 jsonl、csv、npy 或 raw，默认按扩展名推断")
+	flag.IntVar(&opts.BatchSize, "batch-size", 64, "每批嵌入的文本数，每批完成后写入输出")
+	flag.BoolVar(&opts.Resume, "resume", false, "跳过输出中已有的 id，追加写入")
+	flag.BoolVar(&opts.SkipDuplicates, "skip-duplicate-ids", false, "跳过输入中重复的 id（只保留第一条），默认报错")
+	verbose := flag.Bool("v", false, "输出调试日志到标准错误")
+	flag.Parse()
+	opts.Sources = flag.Args()
+
+	if *verbose {
+		embedder.SetDefaultLogger(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, opts, os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "embed: %v\n", err)
+		if errors.Is(err, context.Canceled) && opts.Output != "" {
+			fmt.Fprintln(os.Stderr, "embed: interrupted, rerun with --resume to continue")
+		}
+		os.Exit(1)
+	}
+}
+
+// run 执行一次嵌入任务，进度与统计写入 stderr
+func run(ctx context.Context, opts options, stdin io.Reader, stdout, stderr io.Writer) error {
+	if opts.BatchSize <= 0 {
+		return errors.New("-batch-size must be positive")
+	}
+	if len(opts.Sources) == 0 {
+		opts.Sources = []string{"-"}
+	}
+
+	config, err := loadConfig(opts)
+	if err != nil {
+		return err
+	}
+	e, err := embedder.CreateEmbedderWithConfigContext(ctx, config)
+	if err != nil {
+		return err
+	}
+
+	format := outputFormat(opts.Format, opts.Output)
+	done := map[string]bool{}
+	if opts.Resume {
+		if done, err = completedIDs(opts.Output, format, e.GetDimension()); err != nil {
+			return err
+		}
+	}
+
+	out, err := openWriter(opts.Output, format, stdout, opts.Resume)
+	if err != nil {
+		return err
+	}
+
+	var (
+		batch      []record
+		embedded   int
+		skipped    int
+		duplicates int
+		seen       = map[string]bool{}
+		start      = time.Now()
+	)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		ids := make([]string, len(batch))
+		texts := make([]string, len(batch))
+		for i, rec := range batch {
+			ids[i], texts[i] = rec.ID, rec.Text
+		}
+		vectors, err := e.BatchEmbed(ctx, texts, opts.BatchSize)
+		if err != nil {
+			return err
+		}
+		if err := out.Write(ids, vectors); err != nil {
+			return err
+		}
+		embedded += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	err = readRecords(opts.Sources, stdin, opts.Input, func(rec record) error {
+		if seen[rec.ID] {
+			if !opts.SkipDuplicates {
+				return fmt.Errorf("duplicate id %q, use -skip-duplicate-ids to keep only the first", rec.ID)
+			}
+			duplicates++
+			return nil
+		}
+		seen[rec.ID] = true
+		if done[rec.ID] {
+			skipped++
+			return nil
+		}
+		batch = append(batch, rec)
+		if len(batch) < opts.BatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+
+	fmt.Fprintf(stderr, "embedded %d, skipped %d, duplicates %d (%s, %s)\n",
+		embedded, skipped, duplicates, e.GetModel(), time.Since(start).Round(time.Millisecond))
+	return err
+}
+
+// loadConfig 读取配置文件并应用命令行覆盖
+func loadConfig(opts options) (embedder.Config, error) {
+	config := embedder.DefaultConfig
+	if opts.ConfigPath != "" {
+		loaded, err := embedder.LoadConfig(opts.ConfigPath)
+		if err != nil {
+			return config, err
+		}
+		config = *loaded
+	}
+
+	if opts.Provider != "" {
+		config.Provider = opts.Provider
+	}
+	if opts.BaseURL != "" {
+		config.BaseURL = opts.BaseURL
+	}
+	if opts.Model != "" {
+		config.Model = opts.Model
+	}
+	if opts.Timeout > 0 {
+		config.Timeout = opts.Timeout
+	}
+	return config, nil
+}
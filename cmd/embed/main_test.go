@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	embedder "github.com/Kizunad/modular-embedder"
+)
+
+// embedCalls stub 提供者收到的文本总数
+var embedCalls atomic.Int64
+
+// stubEmbedder 测试用嵌入服务，向量为 [文本长度, 1, 0]
+type stubEmbedder struct{}
+
+func (stubEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embedCalls.Add(int64(len(texts)))
+	result := make([][]float32, len(texts))
+	for i, text := range texts {
+		result[i] = []float32{float32(len(text)), 1, 0}
+	}
+	return result, nil
+}
+
+func (s stubEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	result, err := s.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return result[0], nil
+}
+
+func (s stubEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	return s.Embed(ctx, texts)
+}
+
+func (stubEmbedder) GetDimension() int                { return 3 }
+func (stubEmbedder) GetModel() string                 { return "stub" }
+func (stubEmbedder) Health(ctx context.Context) error { return nil }
+
+func init() {
+	embedder.RegisterProvider("embed-stub", func(config embedder.Config) (embedder.Embedder, error) {
+		return stubEmbedder{}, nil
+	})
+}
+
+func testOptions(output string, sources ...string) options {
+	return options{
+		Provider:  "embed-stub",
+		Input:     inputOptions{Field: "text", IDField: "id"},
+		Output:    output,
+		BatchSize: 2,
+		Sources:   sources,
+	}
+}
+
+func TestRunStdinJSONL(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	stdin := strings.NewReader("hello\n\nworld!\n")
+	if err := run(context.Background(), testOptions(""), stdin, &stdout, &stderr); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 output lines, got %q", stdout.String())
+	}
+	var line jsonlLine
+	json.Unmarshal([]byte(lines[1]), &line)
+	// 空行不输出，但行号保持不变
+	if line.ID != "3" || line.Embedding[0] != 6 {
+		t.Errorf("Unexpected line: %+v", line)
+	}
+	if !strings.Contains(stderr.String(), "embedded 2") {
+		t.Errorf("Unexpected summary: %s", stderr.String())
+	}
+}
+
+func TestRunJSONLInputCSVOutput(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "docs.jsonl")
+	os.WriteFile(input, []byte(`{"id": "a", "body": "one"}
+{"id": 7, "body": "three"}
+{"body": "no id"}
+`), 0o644)
+
+	output := filepath.Join(dir, "out.csv")
+	opts := testOptions(output, input)
+	opts.Input.Field = "body"
+	if err := run(context.Background(), opts, nil, nil, &bytes.Buffer{}); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(output)
+	want := "a,3,1,0\n7,5,1,0\n3,5,1,0\n"
+	if string(data) != want {
+		t.Errorf("Expected %q, got %q", want, data)
+	}
+
+	opts.Input.Field = "missing"
+	if err := run(context.Background(), opts, nil, nil, &bytes.Buffer{}); err == nil {
+		t.Error("Expected error for missing field")
+	}
+}
+
+func TestRunNPYOutput(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "docs.txt")
+	os.WriteFile(input, []byte("a\nbb\nccc\n"), 0o644)
+
+	output := filepath.Join(dir, "out.npy")
+	if err := run(context.Background(), testOptions(output, input), nil, nil, &bytes.Buffer{}); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(output)
+	if len(data) != npyHeaderSize+3*3*4 || !bytes.HasPrefix(data, []byte("\x93NUMPY")) {
+		t.Fatalf("Unexpected npy size %d", len(data))
+	}
+	if !bytes.Contains(data[:npyHeaderSize], []byte("'shape': (3, 3)")) {
+		t.Errorf("Unexpected header %q", data[:npyHeaderSize])
+	}
+	if (npyHeaderSize)%64 != 0 || data[npyHeaderSize-1] != '\n' {
+		t.Error("npy header must be 64-byte aligned and end with newline")
+	}
+	ids, _ := os.ReadFile(idsPath(output))
+	if string(ids) != "1\n2\n3\n" {
+		t.Errorf("Unexpected ids %q", ids)
+	}
+}
+
+func TestRunInputIDs(t *testing.T) {
+	// 含换行的 id 会破坏 .ids 文件
+	stdin := strings.NewReader(`{"id": "a\nb", "text": "x"}` + "\n")
+	opts := testOptions("")
+	opts.Input.Format = "jsonl"
+	if err := run(context.Background(), opts, stdin, &bytes.Buffer{}, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "line break") {
+		t.Errorf("Expected error for id with a line break, got %v", err)
+	}
+
+	duplicated := `{"id": "a", "text": "one"}
+{"id": "a", "text": "two"}
+{"id": "b", "text": "three"}
+`
+	err := run(context.Background(), opts, strings.NewReader(duplicated), &bytes.Buffer{}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "duplicate id") {
+		t.Errorf("Expected duplicate id error, got %v", err)
+	}
+
+	opts.SkipDuplicates = true
+	var stdout, stderr bytes.Buffer
+	if err := run(context.Background(), opts, strings.NewReader(duplicated), &stdout, &stderr); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if lines := strings.Count(stdout.String(), "\n"); lines != 2 || !strings.Contains(stderr.String(), "duplicates 1") {
+		t.Errorf("Expected 2 lines and 1 duplicate, got %d lines: %s", lines, stderr.String())
+	}
+}
+
+func TestRunResume(t *testing.T) {
+	for _, format := range []string{formatJSONL, formatCSV, formatNPY, formatRaw} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			input := filepath.Join(dir, "docs.txt")
+			output := filepath.Join(dir, "out."+format)
+			opts := testOptions(output, input)
+			opts.Format = format
+
+			// 第一次只有前两行
+			os.WriteFile(input, []byte("a\nbb\n"), 0o644)
+			if err := run(context.Background(), opts, nil, nil, &bytes.Buffer{}); err != nil {
+				t.Fatalf("run failed: %v", err)
+			}
+
+			// 模拟中断：输出末尾写了一半
+			f, _ := os.OpenFile(output, os.O_APPEND|os.O_WRONLY, 0o644)
+			f.Write([]byte("partial"))
+			f.Close()
+
+			os.WriteFile(input, []byte("a\nbb\nccc\ndddd\n"), 0o644)
+			opts.Resume = true
+			embedCalls.Store(0)
+			var stderr bytes.Buffer
+			if err := run(context.Background(), opts, nil, nil, &stderr); err != nil {
+				t.Fatalf("resume failed: %v", err)
+			}
+			if embedCalls.Load() != 2 || !strings.Contains(stderr.String(), "skipped 2") {
+				t.Errorf("Expected only new lines to be embedded, got %d calls: %s", embedCalls.Load(), stderr.String())
+			}
+
+			data, _ := os.ReadFile(output)
+			switch format {
+			case formatJSONL, formatCSV:
+				lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+				if len(lines) != 4 || strings.Contains(string(data), "partial") {
+					t.Errorf("Unexpected output %q", data)
+				}
+			case formatNPY:
+				if len(data) != npyHeaderSize+4*3*4 || !bytes.Contains(data, []byte("(4, 3)")) {
+					t.Errorf("Unexpected npy size %d", len(data))
+				}
+			case formatRaw:
+				if len(data) != 4*3*4 {
+					t.Fatalf("Unexpected raw size %d", len(data))
+				}
+				last := make([]float32, 3)
+				binary.Read(bytes.NewReader(data[3*3*4:]), binary.LittleEndian, last)
+				if last[0] != 4 {
+					t.Errorf("Unexpected last vector %v", last)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,424 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 输出格式
+const (
+	formatJSONL = "jsonl"
+	formatCSV   = "csv"
+	formatNPY   = "npy"
+	formatRaw   = "raw"
+)
+
+// npyHeaderSize 固定的 .npy 头部长度，预留足够空间以便结束时原地改写 shape
+const npyHeaderSize = 128
+
+// writer 嵌入结果输出
+type writer interface {
+	// Write 写入一批结果，返回前应已落盘，中断后可以 --resume
+	Write(ids []string, vectors [][]float32) error
+	// Close 完成输出
+	Close() error
+}
+
+// outputFormat 根据扩展名推断输出格式
+func outputFormat(format, path string) string {
+	if format != "" {
+		return format
+	}
+	switch {
+	case strings.HasSuffix(path, ".csv"):
+		return formatCSV
+	case strings.HasSuffix(path, ".npy"):
+		return formatNPY
+	case strings.HasSuffix(path, ".bin"), strings.HasSuffix(path, ".f32"):
+		return formatRaw
+	default:
+		return formatJSONL
+	}
+}
+
+// idsPath npy 和 raw 输出的 id 文件，每行一个 id，与向量按行对应
+func idsPath(path string) string {
+	return path + ".ids"
+}
+
+// openWriter 创建输出，path 为空时写入 stdout
+// resume 为 true 时追加到已有文件之后
+func openWriter(path, format string, stdout io.Writer, resume bool) (writer, error) {
+	if path == "" {
+		switch format {
+		case formatJSONL, formatCSV, formatRaw:
+			return newStreamWriter(format, nopCloser{stdout}, nil), nil
+		default:
+			return nil, fmt.Errorf("%s output requires -o", format)
+		}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if resume {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+
+	if format == formatNPY {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		ids, err := os.OpenFile(idsPath(path), flags, 0o644)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		return newNPYWriter(file, ids, resume)
+	}
+
+	file, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	var ids io.WriteCloser
+	if format == formatRaw {
+		if ids, err = os.OpenFile(idsPath(path), flags, 0o644); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	switch format {
+	case formatJSONL, formatCSV, formatRaw:
+		return newStreamWriter(format, file, ids), nil
+	default:
+		file.Close()
+		return nil, fmt.Errorf("unknown output format: %s", format)
+	}
+}
+
+// nopCloser 不关闭 stdout
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// streamWriter 逐条追加的 jsonl、csv、raw 输出
+type streamWriter struct {
+	format string
+	out    io.WriteCloser
+	ids    io.WriteCloser
+	buf    *bufio.Writer
+	idBuf  *bufio.Writer
+}
+
+func newStreamWriter(format string, out, ids io.WriteCloser) *streamWriter {
+	w := &streamWriter{format: format, out: out, ids: ids, buf: bufio.NewWriter(out)}
+	if ids != nil {
+		w.idBuf = bufio.NewWriter(ids)
+	}
+	return w
+}
+
+// jsonlLine jsonl 输出的一行
+type jsonlLine struct {
+	ID        string    `json:"id"`
+	Embedding []float32 `json:"embedding"`
+}
+
+func (w *streamWriter) Write(ids []string, vectors [][]float32) error {
+	for i, vector := range vectors {
+		var err error
+		switch w.format {
+		case formatJSONL:
+			err = json.NewEncoder(w.buf).Encode(jsonlLine{ID: ids[i], Embedding: vector})
+		case formatCSV:
+			err = writeCSVRow(w.buf, ids[i], vector)
+		case formatRaw:
+			err = binary.Write(w.buf, binary.LittleEndian, vector)
+		}
+		if err != nil {
+			return err
+		}
+		if w.idBuf != nil {
+			if _, err := w.idBuf.WriteString(ids[i] + "\n"); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 先写向量再写 id，中断时 id 文件不会领先于向量
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if w.idBuf != nil {
+		return w.idBuf.Flush()
+	}
+	return nil
+}
+
+func (w *streamWriter) Close() error {
+	err := w.out.Close()
+	if w.ids != nil {
+		if idErr := w.ids.Close(); err == nil {
+			err = idErr
+		}
+	}
+	return err
+}
+
+// writeCSVRow 写入一行：id 后接各维度的值
+func writeCSVRow(w io.Writer, id string, vector []float32) error {
+	row := make([]string, len(vector)+1)
+	row[0] = id
+	for i, v := range vector {
+		row[i+1] = strconv.FormatFloat(float64(v), 'g', -1, 32)
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(row)
+	cw.Flush()
+	return cw.Error()
+}
+
+// npyWriter 写入 (N, D) 的 float32 .npy 文件
+// 头部在第一次写入时按 N=0 写出，Close 时改写为实际行数
+type npyWriter struct {
+	file  *os.File
+	ids   io.WriteCloser
+	rows  int
+	dim   int
+	buf   *bufio.Writer
+	idBuf *bufio.Writer
+}
+
+func newNPYWriter(file *os.File, ids io.WriteCloser, resume bool) (*npyWriter, error) {
+	w := &npyWriter{file: file, ids: ids, buf: bufio.NewWriter(file), idBuf: bufio.NewWriter(ids)}
+	if resume {
+		rows, dim, err := npyShape(file)
+		if err != nil {
+			w.Close()
+			return nil, err
+		}
+		w.rows, w.dim = rows, dim
+	} else if err := file.Truncate(0); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		w.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *npyWriter) Write(ids []string, vectors [][]float32) error {
+	if len(vectors) == 0 {
+		return nil
+	}
+	if w.dim == 0 {
+		w.dim = len(vectors[0])
+		if _, err := w.buf.Write(npyHeader(0, w.dim)); err != nil {
+			return err
+		}
+	}
+
+	for i, vector := range vectors {
+		if len(vector) != w.dim {
+			return fmt.Errorf("npy output requires a fixed dimension: got %d, expected %d", len(vector), w.dim)
+		}
+		if err := binary.Write(w.buf, binary.LittleEndian, vector); err != nil {
+			return err
+		}
+		if _, err := w.idBuf.WriteString(ids[i] + "\n"); err != nil {
+			return err
+		}
+	}
+	w.rows += len(vectors)
+
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	return w.idBuf.Flush()
+}
+
+func (w *npyWriter) Close() error {
+	var err error
+	if w.dim > 0 {
+		_, err = w.file.WriteAt(npyHeader(w.rows, w.dim), 0)
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := w.ids.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// npyHeader 生成固定长度的 .npy v1.0 头部
+func npyHeader(rows, dim int) []byte {
+	dict := fmt.Sprintf("{'descr': '<f4', 'fortran_order': False, 'shape': (%d, %d), }", rows, dim)
+	header := make([]byte, npyHeaderSize)
+	copy(header, "\x93NUMPY\x01\x00")
+	binary.LittleEndian.PutUint16(header[8:], npyHeaderSize-10)
+	n := copy(header[10:], dict)
+	for i := 10 + n; i < npyHeaderSize-1; i++ {
+		header[i] = ' '
+	}
+	header[npyHeaderSize-1] = '\n'
+	return header
+}
+
+// npyShapePattern 匹配头部中的 shape
+var npyShapePattern = regexp.MustCompile(`'shape': \((\d+), (\d+)\)`)
+
+// npyShape 读取已有 .npy 文件的维度，行数按文件大小计算（中断时头部中的行数未更新）
+func npyShape(file *os.File) (rows, dim int, err error) {
+	info, err := file.Stat()
+	if err != nil || info.Size() < npyHeaderSize {
+		return 0, 0, err
+	}
+
+	header := make([]byte, npyHeaderSize)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return 0, 0, fmt.Errorf("read npy header: %w", err)
+	}
+	match := npyShapePattern.FindSubmatch(header)
+	if match == nil {
+		return 0, 0, errors.New("unsupported npy header")
+	}
+	dim, _ = strconv.Atoi(string(match[2]))
+	if dim == 0 {
+		return 0, 0, nil
+	}
+	return int(info.Size()-npyHeaderSize) / (4 * dim), dim, nil
+}
+
+// completedIDs 读取已有输出中完成的 id，并截掉中断时写了一半的内容
+func completedIDs(path, format string, dim int) (map[string]bool, error) {
+	done := make(map[string]bool)
+	if path == "" {
+		return nil, errors.New("--resume requires -o")
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return done, nil
+	}
+
+	switch format {
+	case formatJSONL, formatCSV:
+		lines, err := completeLines(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range lines {
+			id, err := lineID(line, format)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			done[id] = true
+		}
+		return done, nil
+
+	case formatNPY, formatRaw:
+		ids, err := completeLines(idsPath(path))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+
+		offset, rowSize := int64(0), int64(4*dim)
+		if format == formatNPY {
+			file, err := os.Open(path)
+			if err != nil {
+				return nil, err
+			}
+			_, dim, err = npyShape(file)
+			file.Close()
+			if err != nil {
+				return nil, err
+			}
+			offset, rowSize = npyHeaderSize, int64(4*dim)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case rowSize == 0 && format == formatNPY:
+			// 头部尚未完整写出，从头开始
+			ids = nil
+			offset = 0
+		case rowSize == 0 && info.Size() > 0:
+			return nil, errors.New("--resume with raw output requires a known dimension")
+		default:
+			// 向量和 id 取两者中较少的行数，多出的部分截掉
+			if rows := int((info.Size() - offset) / max(rowSize, 1)); rows < len(ids) {
+				ids = ids[:rows]
+			}
+		}
+		if err := os.Truncate(path, offset+int64(len(ids))*rowSize); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(idsPath(path), []byte(joinLines(ids)), 0o644); err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			done[id] = true
+		}
+		return done, nil
+	}
+	return nil, fmt.Errorf("unknown output format: %s", format)
+}
+
+// completeLines 读取以换行结尾的完整行，并截掉末尾不完整的一行
+func completeLines(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	end := strings.LastIndexByte(string(data), '\n') + 1
+	if end < len(data) {
+		if err := os.Truncate(path, int64(end)); err != nil {
+			return nil, err
+		}
+	}
+	if end == 0 {
+		return nil, nil
+	}
+	return strings.Split(string(data[:end-1]), "\n"), nil
+}
+
+// lineID 取出 jsonl 或 csv 输出中一行的 id
+func lineID(line, format string) (string, error) {
+	if format == formatJSONL {
+		var obj jsonlLine
+		if err := json.Unmarshal([]byte(line), &obj); err != nil {
+			return "", err
+		}
+		return obj.ID, nil
+	}
+	row, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil {
+		return "", err
+	}
+	return row[0], nil
+}
+
+// joinLines 将行拼接为以换行结尾的文本
+func joinLines(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
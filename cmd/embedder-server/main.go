@@ -0,0 +1,175 @@
+// embedder-server 以 OpenAI 兼容的 HTTP 接口提供嵌入服务
+//
+// 每个 -config 加载一个 YAML 配置（格式同 embedder.LoadConfig），可重复指定以提供多个模型：
+//
+//	embedder-server -addr :8080 \
+//	    -config nomic=configs/nomic.yaml \
+//	    -config openai-small=configs/openai.yaml \
+//	    -default nomic
+//
+// 省略 "名称=" 时使用配置中的模型名。请求中的 model 字段按名称路由。
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	embedder "github.com/Kizunad/modular-embedder"
+)
+
+// configFlags 可重复的 -config 参数
+type configFlags []string
+
+func (c *configFlags) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *configFlags) Set(value string) error {
+	*c = append(*c, value)
+	return nil
+}
+
+func main() {
+	var configs configFlags
+	flag.Var(&configs, "config", "模型配置，格式为 [名称=]路径，可重复指定")
+	addr := flag.String("addr", ":8080", "监听地址")
+	defaultModel := flag.String("default", "", "请求未指定 model 时使用的模型名")
+	apiKey := flag.String("api-key", os.Getenv("EMBEDDER_SERVER_API_KEY"), "客户端需携带的 Bearer token，为空时不校验")
+	cacheSize := flag.Int("cache-size", 10000, "每个模型的内存缓存条目数，0 表示不缓存")
+	cacheDir := flag.String("cache-dir", "", "持久化缓存目录，设置后每个模型使用一个缓存文件")
+	batchSize := flag.Int("batch-size", 64, "向提供者发送请求的批大小")
+	maxInputs := flag.Int("max-inputs", 2048, "单个请求的最大输入数")
+	maxBodyBytes := flag.Int64("max-body-bytes", 16<<20, "请求体的最大字节数")
+	startupTimeout := flag.Duration("startup-timeout", time.Minute, "创建嵌入服务的超时时间")
+	logLevel := flag.String("log-level", "info", "日志级别 debug、info、warn 或 error")
+	flag.Parse()
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(*logLevel)); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -log-level: %v\n", err)
+		os.Exit(2)
+	}
+	// 服务端输出 JSON 日志，包含 component 和 request_id 字段
+	embedder.SetDefaultLogger(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+
+	if len(configs) == 0 {
+		fmt.Fprintln(os.Stderr, "at least one -config is required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *startupTimeout)
+	registry := embedder.NewRegistry()
+	models, closers, err := loadModels(ctx, configs, *cacheSize, *cacheDir, registry)
+	cancel()
+	if err != nil {
+		log.Fatalf("加载模型失败: %v", err)
+	}
+	defer func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}()
+
+	if *defaultModel != "" {
+		if _, ok := models[*defaultModel]; !ok {
+			log.Fatalf("默认模型 %q 未配置", *defaultModel)
+		}
+	}
+
+	srv := newServer(models, *defaultModel)
+	srv.apiKey = *apiKey
+	srv.batchSize = *batchSize
+	srv.maxInputs = *maxInputs
+	srv.maxBodyBytes = *maxBodyBytes
+	srv.metrics = registry.Handler()
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           srv.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+		<-stop
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	srv.logger.Info("服务已启动", embedder.String("addr", *addr), embedder.String("models", strings.Join(srv.modelNames(), ",")))
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("服务异常退出: %v", err)
+	}
+}
+
+// loadModels 按 -config 参数创建各模型的嵌入服务，返回需要在退出时关闭的缓存
+// 每个模型的指标记录到 metrics，provider 标签为配置中的名称
+func loadModels(ctx context.Context, configs []string, cacheSize int, cacheDir string, metrics embedder.Metrics) (map[string]embedder.Embedder, []*embedder.CacheEmbedder, error) {
+	models := make(map[string]embedder.Embedder, len(configs))
+	var closers []*embedder.CacheEmbedder
+
+	for _, spec := range configs {
+		name, path := "", spec
+		if i := strings.Index(spec, "="); i >= 0 {
+			name, path = spec[:i], spec[i+1:]
+		}
+
+		config, err := embedder.LoadConfig(path)
+		if err != nil {
+			return nil, closers, fmt.Errorf("load %s: %w", path, err)
+		}
+		if name == "" {
+			name = config.Model
+		}
+		if _, ok := models[name]; ok {
+			return nil, closers, fmt.Errorf("duplicate model name: %s", name)
+		}
+
+		e, err := embedder.CreateEmbedderWithConfigContext(ctx, *config)
+		if err != nil {
+			return nil, closers, fmt.Errorf("create %s: %w", name, err)
+		}
+
+		store, err := openCache(name, cacheSize, cacheDir)
+		if err != nil {
+			return nil, closers, err
+		}
+		if store != nil {
+			cached := embedder.NewCacheEmbedder(e, store, config.Provider)
+			closers = append(closers, cached)
+			e = cached
+		}
+
+		models[name] = embedder.NewMetricsEmbedder(e, metrics, name)
+	}
+	return models, closers, nil
+}
+
+// openCache 按参数打开缓存，都未设置时返回 nil
+func openCache(name string, cacheSize int, cacheDir string) (embedder.CacheStore, error) {
+	if cacheDir != "" {
+		if err := os.MkdirAll(cacheDir, 0o755); err != nil {
+			return nil, err
+		}
+		safe := strings.NewReplacer("/", "_", ":", "_", "\\", "_").Replace(name)
+		return embedder.OpenFileCache(filepath.Join(cacheDir, safe+".cache"))
+	}
+	if cacheSize > 0 {
+		return embedder.NewMemoryCache(cacheSize), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	embedder "github.com/Kizunad/modular-embedder"
+)
+
+// embeddingRequest OpenAI /v1/embeddings 请求体
+type embeddingRequest struct {
+	Input          json.RawMessage `json:"input"`
+	Model          string          `json:"model"`
+	EncodingFormat string          `json:"encoding_format"`
+	Dimensions     int             `json:"dimensions"`
+	User           string          `json:"user"`
+}
+
+// embeddingData 单个嵌入结果，Embedding 为 []float32 或 base64 字符串
+type embeddingData struct {
+	Object    string      `json:"object"`
+	Index     int         `json:"index"`
+	Embedding interface{} `json:"embedding"`
+}
+
+// embeddingUsage token 用量，按 embedder.ApproxTokens 估算
+type embeddingUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// embeddingResponse OpenAI /v1/embeddings 响应体
+type embeddingResponse struct {
+	Object string          `json:"object"`
+	Data   []embeddingData `json:"data"`
+	Model  string          `json:"model"`
+	Usage  embeddingUsage  `json:"usage"`
+}
+
+// modelObject /v1/models 中的模型条目
+type modelObject struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// apiError OpenAI 格式的错误响应
+type apiError struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param"`
+	Code    string  `json:"code,omitempty"`
+}
+
+// server 按模型名路由到不同嵌入服务的 HTTP 服务
+type server struct {
+	models       map[string]embedder.Embedder
+	defaultModel string
+	apiKey       string
+	batchSize    int
+	maxInputs    int
+	maxBodyBytes int64
+	metrics      http.Handler
+	logger       *embedder.Logger
+}
+
+// newServer 创建服务，defaultModel 为空且只有一个模型时默认使用该模型
+func newServer(models map[string]embedder.Embedder, defaultModel string) *server {
+	if defaultModel == "" && len(models) == 1 {
+		for name := range models {
+			defaultModel = name
+		}
+	}
+	return &server{
+		models:       models,
+		defaultModel: defaultModel,
+		batchSize:    64,
+		maxInputs:    2048,
+		maxBodyBytes: 16 << 20,
+		logger:       embedder.NewLogger("embedder-server"),
+	}
+}
+
+// handler 注册路由
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/embeddings", s.authorize(s.handleEmbeddings))
+	mux.HandleFunc("/v1/models", s.authorize(s.handleModels))
+	mux.HandleFunc("/health", s.handleHealth)
+	// 指标包含模型名和请求量，与嵌入接口使用相同的认证
+	if s.metrics != nil {
+		mux.HandleFunc("/metrics", s.authorize(s.metrics.ServeHTTP))
+	}
+	return withRequestID(mux)
+}
+
+// withRequestID 为每个请求附带请求ID，优先使用客户端的 X-Request-ID
+// 请求ID随 ctx 传入嵌入服务，出现在所有日志的 request_id 字段中
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(embedder.WithRequestID(r.Context(), id)))
+	})
+}
+
+// newRequestID 生成随机请求ID
+func newRequestID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// authorize 设置了 apiKey 时校验 Bearer token，按常量时间比较
+func (s *server) authorize(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.apiKey != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+s.apiKey)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid_request_error", "invalid_api_key", "invalid API key")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleEmbeddings 处理嵌入请求
+func (s *server) handleEmbeddings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "invalid_request_error", "", "method not allowed")
+		return
+	}
+
+	// 在读取之前限制请求体大小，max-inputs 只能在解析完成后检查
+	var req embeddingRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.maxBodyBytes)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, "invalid_request_error", "",
+				fmt.Sprintf("request body too large, maximum is %d bytes", s.maxBodyBytes))
+			return
+		}
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", "invalid JSON body: "+err.Error())
+		return
+	}
+
+	texts, err := parseInput(req.Input)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "", err.Error())
+		return
+	}
+	if len(texts) > s.maxInputs {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "",
+			fmt.Sprintf("too many inputs: %d, maximum is %d", len(texts), s.maxInputs))
+		return
+	}
+	if req.EncodingFormat != "" && req.EncodingFormat != "float" && req.EncodingFormat != "base64" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "",
+			"unsupported encoding_format: "+req.EncodingFormat)
+		return
+	}
+
+	name, e, ok := s.lookup(req.Model)
+	if !ok {
+		writeError(w, http.StatusNotFound, "invalid_request_error", "model_not_found",
+			fmt.Sprintf("model %q not found, available: %s", req.Model, strings.Join(s.modelNames(), ", ")))
+		return
+	}
+	if req.Dimensions < 0 || (req.Dimensions > 0 && e.GetDimension() > 0 && req.Dimensions > e.GetDimension()) {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "",
+			fmt.Sprintf("dimensions must be between 1 and %d", e.GetDimension()))
+		return
+	}
+
+	start := time.Now()
+	embeddings, err := e.BatchEmbed(r.Context(), texts, s.batchSize)
+	if err != nil {
+		s.logger.ErrorContext(r.Context(), "嵌入失败",
+			embedder.String("model", name), embedder.Int("inputs", len(texts)), embedder.Error(err))
+		s.writeEmbedError(w, err)
+		return
+	}
+	s.logger.InfoContext(r.Context(), "嵌入完成", embedder.String("model", name), embedder.Int("inputs", len(texts)),
+		embedder.Duration("duration", time.Since(start)))
+
+	resp := embeddingResponse{
+		Object: "list",
+		Data:   make([]embeddingData, len(embeddings)),
+		Model:  name,
+	}
+	for i, embedding := range embeddings {
+		if req.Dimensions > 0 {
+			embedding = shorten(embedding, req.Dimensions)
+		}
+		data := embeddingData{Object: "embedding", Index: i, Embedding: embedding}
+		if req.EncodingFormat == "base64" {
+			data.Embedding = encodeBase64(embedding)
+		}
+		resp.Data[i] = data
+		resp.Usage.PromptTokens += embedder.ApproxTokens(texts[i])
+	}
+	resp.Usage.TotalTokens = resp.Usage.PromptTokens
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleModels 列出可用的模型名
+func (s *server) handleModels(w http.ResponseWriter, r *http.Request) {
+	names := s.modelNames()
+	data := make([]modelObject, len(names))
+	for i, name := range names {
+		data[i] = modelObject{ID: name, Object: "model", OwnedBy: "embedder-server"}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"object": "list", "data": data})
+}
+
+// handleHealth 对所有模型（或 ?model= 指定的模型）执行健康检查
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	names := s.modelNames()
+	if model := r.URL.Query().Get("model"); model != "" {
+		name, _, ok := s.lookup(model)
+		if !ok {
+			writeError(w, http.StatusNotFound, "invalid_request_error", "model_not_found",
+				fmt.Sprintf("model %q not found", model))
+			return
+		}
+		names = []string{name}
+	}
+
+	status := http.StatusOK
+	results := make(map[string]string, len(names))
+	for _, name := range names {
+		if err := s.models[name].Health(r.Context()); err != nil {
+			status = http.StatusServiceUnavailable
+			results[name] = err.Error()
+			continue
+		}
+		results[name] = "ok"
+	}
+
+	overall := "ok"
+	if status != http.StatusOK {
+		overall = "unavailable"
+	}
+	writeJSON(w, status, map[string]interface{}{"status": overall, "models": results})
+}
+
+// lookup 按请求中的模型名查找嵌入服务
+// 先匹配配置中的名称，再匹配底层模型名，为空时使用默认模型
+func (s *server) lookup(model string) (string, embedder.Embedder, bool) {
+	if model == "" {
+		model = s.defaultModel
+	}
+	if e, ok := s.models[model]; ok {
+		return model, e, true
+	}
+	for _, name := range s.modelNames() {
+		if s.models[name].GetModel() == model {
+			return name, s.models[name], true
+		}
+	}
+	return "", nil, false
+}
+
+// modelNames 按字母顺序返回模型名
+func (s *server) modelNames() []string {
+	names := make([]string, 0, len(s.models))
+	for name := range s.models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writeEmbedError 将嵌入错误映射为 HTTP 状态码
+func (s *server) writeEmbedError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, embedder.ErrInputTooLong):
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "context_length_exceeded", err.Error())
+	case errors.Is(err, embedder.ErrRateLimited):
+		writeError(w, http.StatusTooManyRequests, "rate_limit_error", "rate_limit_exceeded", err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		writeError(w, http.StatusGatewayTimeout, "server_error", "timeout", err.Error())
+	case errors.Is(err, embedder.ErrUnavailable):
+		writeError(w, http.StatusServiceUnavailable, "server_error", "upstream_unavailable", err.Error())
+	case errors.Is(err, embedder.ErrModelNotFound), errors.Is(err, embedder.ErrUnauthorized),
+		errors.Is(err, embedder.ErrInvalidResponse):
+		// 上游配置或响应问题，对调用方而言是网关错误
+		writeError(w, http.StatusBadGateway, "server_error", "upstream_error", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "server_error", "", err.Error())
+	}
+}
+
+// parseInput 解析 input 字段，支持字符串或字符串数组
+func parseInput(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, errors.New("input is required")
+	}
+
+	var single string
+	if err := json.Unmarshal(raw, &single); err == nil {
+		return []string{single}, nil
+	}
+
+	var texts []string
+	if err := json.Unmarshal(raw, &texts); err != nil {
+		return nil, errors.New("input must be a string or an array of strings; token arrays are not supported")
+	}
+	if len(texts) == 0 {
+		return nil, errors.New("input must not be empty")
+	}
+	return texts, nil
+}
+
+// shorten 截断到指定维度并重新归一化，复制后处理以免修改缓存中的向量
+func shorten(embedding []float32, dims int) []float32 {
+	vector := make([]float32, len(embedding))
+	copy(vector, embedding)
+	vector = embedder.TruncateDims(dims).Apply(vector)
+	return embedder.Normalize().Apply(vector)
+}
+
+// encodeBase64 按小端 float32 编码，与 OpenAI 的 base64 格式一致
+func encodeBase64(embedding []float32) string {
+	buf := make([]byte, 4*len(embedding))
+	for i, v := range embedding {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(v))
+	}
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// writeJSON 写入 JSON 响应
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError 写入 OpenAI 格式的错误响应
+func writeError(w http.ResponseWriter, status int, errType, code, message string) {
+	writeJSON(w, status, map[string]apiError{
+		"error": {Message: message, Type: errType, Code: code},
+	})
+}
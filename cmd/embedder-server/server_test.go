@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	embedder "github.com/Kizunad/modular-embedder"
+)
+
+// stubEmbedder 返回固定维度向量的测试嵌入服务，第一维为文本长度
+type stubEmbedder struct {
+	model string
+	dim   int
+	err   error
+}
+
+func (s *stubEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	result := make([][]float32, len(texts))
+	for i, text := range texts {
+		result[i] = make([]float32, s.dim)
+		result[i][0] = float32(len(text))
+		result[i][1] = 1
+	}
+	return result, nil
+}
+
+func (s *stubEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	result, err := s.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return result[0], nil
+}
+
+func (s *stubEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	return s.Embed(ctx, texts)
+}
+
+func (s *stubEmbedder) GetDimension() int { return s.dim }
+
+func (s *stubEmbedder) GetModel() string { return s.model }
+
+func (s *stubEmbedder) Health(ctx context.Context) error { return s.err }
+
+func newTestServer() *server {
+	return newServer(map[string]embedder.Embedder{
+		"small": &stubEmbedder{model: "small-v1", dim: 4},
+		"large": &stubEmbedder{model: "large-v1", dim: 8},
+		"down":  &stubEmbedder{model: "down-v1", dim: 4, err: &embedder.ProviderError{Provider: "stub", Kind: embedder.ErrUnavailable, Index: -1}},
+	}, "small")
+}
+
+func post(t *testing.T, h http.Handler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestEmbeddingsRouting(t *testing.T) {
+	h := newTestServer().handler()
+
+	tests := []struct {
+		body  string
+		model string
+		dim   int
+		count int
+	}{
+		{`{"input": "hello"}`, "small", 4, 1},
+		{`{"input": ["a", "bb"], "model": "large"}`, "large", 8, 2},
+		{`{"input": "hi", "model": "large-v1"}`, "large", 8, 1},
+	}
+
+	for _, tt := range tests {
+		rec := post(t, h, tt.body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", tt.body, rec.Code, rec.Body)
+		}
+		var resp struct {
+			Object string `json:"object"`
+			Model  string `json:"model"`
+			Data   []struct {
+				Object    string    `json:"object"`
+				Index     int       `json:"index"`
+				Embedding []float32 `json:"embedding"`
+			} `json:"data"`
+			Usage embeddingUsage `json:"usage"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response: %v", err)
+		}
+		if resp.Object != "list" || resp.Model != tt.model || len(resp.Data) != tt.count {
+			t.Errorf("%s: unexpected response %+v", tt.body, resp)
+		}
+		for i, d := range resp.Data {
+			if d.Object != "embedding" || d.Index != i || len(d.Embedding) != tt.dim {
+				t.Errorf("%s: unexpected data %+v", tt.body, d)
+			}
+		}
+		if resp.Usage.PromptTokens == 0 || resp.Usage.TotalTokens != resp.Usage.PromptTokens {
+			t.Errorf("%s: unexpected usage %+v", tt.body, resp.Usage)
+		}
+	}
+}
+
+func TestEmbeddingsEncodingAndDimensions(t *testing.T) {
+	h := newTestServer().handler()
+
+	rec := post(t, h, `{"input": "abc", "encoding_format": "base64", "dimensions": 2}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Data []struct {
+			Embedding string `json:"embedding"`
+		} `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &resp)
+	raw, err := base64.StdEncoding.DecodeString(resp.Data[0].Embedding)
+	if err != nil || len(raw) != 8 {
+		t.Fatalf("expected 2 base64 float32s, got %d bytes (%v)", len(raw), err)
+	}
+	x := math.Float32frombits(binary.LittleEndian.Uint32(raw))
+	y := math.Float32frombits(binary.LittleEndian.Uint32(raw[4:]))
+	if norm := math.Sqrt(float64(x*x + y*y)); math.Abs(norm-1) > 1e-5 {
+		t.Errorf("expected shortened vector to be normalized, norm %f", norm)
+	}
+}
+
+func TestEmbeddingsErrors(t *testing.T) {
+	h := newTestServer().handler()
+
+	tests := []struct {
+		body   string
+		status int
+		code   string
+	}{
+		{`{"input": "x", "model": "missing"}`, http.StatusNotFound, "model_not_found"},
+		{`{"input": []}`, http.StatusBadRequest, ""},
+		{`{"input": [[1, 2, 3]]}`, http.StatusBadRequest, ""},
+		{`{"model": "small"}`, http.StatusBadRequest, ""},
+		{`not json`, http.StatusBadRequest, ""},
+		{`{"input": "x", "encoding_format": "int8"}`, http.StatusBadRequest, ""},
+		{`{"input": "x", "dimensions": 100}`, http.StatusBadRequest, ""},
+		{`{"input": "x", "model": "down"}`, http.StatusServiceUnavailable, "upstream_unavailable"},
+	}
+
+	for _, tt := range tests {
+		rec := post(t, h, tt.body)
+		if rec.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.body, tt.status, rec.Code)
+			continue
+		}
+		var resp map[string]apiError
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp["error"].Message == "" {
+			t.Errorf("%s: expected OpenAI error body, got %s", tt.body, rec.Body)
+		}
+		if resp["error"].Code != tt.code {
+			t.Errorf("%s: expected code %q, got %q", tt.body, tt.code, resp["error"].Code)
+		}
+	}
+}
+
+func TestEmbeddingsBodyLimit(t *testing.T) {
+	srv := newTestServer()
+	srv.maxBodyBytes = 64
+	h := srv.handler()
+
+	rec := post(t, h, `{"input": "`+strings.Repeat("x", 100)+`"}`)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for oversized body, got %d: %s", rec.Code, rec.Body)
+	}
+	if rec := post(t, h, `{"input": "x"}`); rec.Code != http.StatusOK {
+		t.Errorf("Expected small body to succeed, got %d: %s", rec.Code, rec.Body)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	var logs bytes.Buffer
+	embedder.SetDefaultLogger(slog.New(slog.NewJSONHandler(&logs, nil)))
+	defer embedder.SetDefaultLogger(nil)
+
+	h := newTestServer().handler()
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(`{"input": "x", "model": "down"}`))
+	req.Header.Set("X-Request-ID", "req-123")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Header().Get("X-Request-ID") != "req-123" {
+		t.Errorf("Expected request id to be echoed, got %q", rec.Header().Get("X-Request-ID"))
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected one JSON log line, got %q", logs.String())
+	}
+	if entry["request_id"] != "req-123" || entry["level"] != "ERROR" || entry["model"] != "down" {
+		t.Errorf("Unexpected log entry: %v", entry)
+	}
+
+	rec = post(t, h, `{"input": "x"}`)
+	if len(rec.Header().Get("X-Request-ID")) != 16 {
+		t.Errorf("Expected generated request id, got %q", rec.Header().Get("X-Request-ID"))
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	srv := newTestServer()
+	srv.apiKey = "secret"
+	h := srv.handler()
+
+	if rec := post(t, h, `{"input": "x"}`); rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without token, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(`{"input": "x"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 with token, got %d", rec.Code)
+	}
+
+	// 健康检查不需要认证
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health?model=small", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected health without token, got %d", rec.Code)
+	}
+}
+
+func TestMetricsAuthorization(t *testing.T) {
+	srv := newTestServer()
+	srv.apiKey = "secret"
+	srv.metrics = embedder.NewRegistry().Handler()
+	h := srv.handler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for metrics without token, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for metrics with token, got %d", rec.Code)
+	}
+}
+
+func TestHealthAndModels(t *testing.T) {
+	h := newTestServer().handler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var health struct {
+		Status string            `json:"status"`
+		Models map[string]string `json:"models"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &health)
+	if rec.Code != http.StatusServiceUnavailable || health.Models["small"] != "ok" || health.Models["down"] == "ok" {
+		t.Errorf("unexpected health response %d: %s", rec.Code, rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	var models struct {
+		Data []modelObject `json:"data"`
+	}
+	json.Unmarshal(rec.Body.Bytes(), &models)
+	if len(models.Data) != 3 || models.Data[0].ID != "down" {
+		t.Errorf("unexpected models response: %s", rec.Body)
+	}
+}
+
+func TestLoadModels(t *testing.T) {
+	embedder.RegisterProvider("server-stub", func(config embedder.Config) (embedder.Embedder, error) {
+		return &stubEmbedder{model: config.Model, dim: 4}, nil
+	})
+
+	dir := t.TempDir()
+	for i, model := range []string{"alpha", "beta"} {
+		content := fmt.Sprintf("provider: server-stub\nmodel: %s\n", model)
+		path := filepath.Join(dir, fmt.Sprintf("%d.yaml", i))
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	specs := []string{filepath.Join(dir, "0.yaml"), "b=" + filepath.Join(dir, "1.yaml")}
+	registry := embedder.NewRegistry()
+	models, closers, err := loadModels(context.Background(), specs, 10, "", registry)
+	if err != nil {
+		t.Fatalf("loadModels failed: %v", err)
+	}
+	if len(closers) != 2 || models["alpha"] == nil || models["b"].GetModel() != "beta" {
+		t.Errorf("unexpected models: %v", models)
+	}
+
+	srv := newServer(models, "b")
+	srv.metrics = registry.Handler()
+	h := srv.handler()
+	post(t, h, `{"input": ["x", "y"]}`)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), `embedder_texts_total{method="batch_embed",model="beta",provider="b"} 2`) {
+		t.Errorf("unexpected metrics output:\n%s", rec.Body)
+	}
+
+	specs = append(specs, "b="+filepath.Join(dir, "0.yaml"))
+	if _, _, err := loadModels(context.Background(), specs, 0, "", registry); err == nil {
+		t.Error("expected duplicate name error")
+	}
+}
@@ -1,7 +1,10 @@
 package embedder
 
 import (
+	"fmt"
+	"log/slog"
 	"os"
+	"strconv"
 	"time"
 
 	"gopkg.in/yaml.v2"
@@ -43,6 +46,49 @@ func (c *EmbedderConfig) WithTimeout(timeout time.Duration) *EmbedderConfig {
 	return c
 }
 
+// WithRetry 设置重试策略
+func (c *EmbedderConfig) WithRetry(policy RetryPolicy) *EmbedderConfig {
+	c.config.Retry = policy
+	return c
+}
+
+// WithOverflow 设置超长输入策略
+func (c *EmbedderConfig) WithOverflow(policy OverflowPolicy) *EmbedderConfig {
+	c.config.Overflow = policy
+	return c
+}
+
+// WithLogger 设置日志
+func (c *EmbedderConfig) WithLogger(logger *slog.Logger) *EmbedderConfig {
+	c.config.Logger = logger
+	return c
+}
+
+// WithBaseURLs 设置多个服务地址，请求按策略负载均衡到各地址
+func (c *EmbedderConfig) WithBaseURLs(baseURLs []string, policy BalancePolicy) *EmbedderConfig {
+	c.config.BaseURLs = baseURLs
+	c.config.Balance = policy
+	return c
+}
+
+// WithLimit 设置客户端限流策略
+func (c *EmbedderConfig) WithLimit(policy LimitPolicy) *EmbedderConfig {
+	c.config.Limit = policy
+	return c
+}
+
+// WithCircuit 设置熔断策略
+func (c *EmbedderConfig) WithCircuit(policy CircuitPolicy) *EmbedderConfig {
+	c.config.Circuit = policy
+	return c
+}
+
+// WithTracer 设置链路追踪钩子
+func (c *EmbedderConfig) WithTracer(tracer Tracer) *EmbedderConfig {
+	c.config.Tracer = tracer
+	return c
+}
+
 // WithOption 设置自定义选项
 func (c *EmbedderConfig) WithOption(key string, value interface{}) *EmbedderConfig {
 	if c.config.Options == nil {
@@ -74,7 +120,8 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
-	var config Config
+	// 预先填入默认重试策略，retry 中未出现的字段保留默认值
+	config := Config{Retry: DefaultConfig.Retry}
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, err
 	}
@@ -95,6 +142,52 @@ func LoadConfig(path string) (*Config, error) {
 	if config.Options == nil {
 		config.Options = make(map[string]interface{})
 	}
+	if config.Retry.MaxAttempts == 0 {
+		// max_attempts: 0 或 retry 为空时按默认次数重试，max_attempts: 1 关闭重试
+		config.Retry.MaxAttempts = DefaultConfig.Retry.MaxAttempts
+	}
 
 	return &config, nil
-}
\ No newline at end of file
+}
+
+// optionString 读取字符串类型的自定义选项
+func optionString(options map[string]interface{}, key, def string) string {
+	value, ok := options[key]
+	if !ok || value == nil {
+		return def
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return fmt.Sprint(value)
+}
+
+// optionInt 读取整数类型的自定义选项，兼容YAML解析出的各种数值类型
+func optionInt(options map[string]interface{}, key string, def int) int {
+	switch v := options[key].(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return def
+}
+
+// optionBool 读取布尔类型的自定义选项
+func optionBool(options map[string]interface{}, key string, def bool) bool {
+	switch v := options[key].(type) {
+	case bool:
+		return v
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return def
+}
@@ -2,8 +2,12 @@ package embedder
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -80,6 +84,61 @@ func TestFactory(t *testing.T) {
 	}
 }
 
+func TestFactoryCreateWithConfigContext(t *testing.T) {
+	factory := NewFactory()
+
+	var received context.Context
+	err := factory.RegisterProviderContext("ctx-mock", func(ctx context.Context, config Config) (Embedder, error) {
+		received = ctx
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return &MockEmbedder{}, nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to register context provider: %v", err)
+	}
+
+	type ctxKey struct{}
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	if _, err := factory.CreateWithConfigContext(ctx, Config{Provider: "ctx-mock"}); err != nil {
+		t.Fatalf("CreateWithConfigContext failed: %v", err)
+	}
+	if received == nil || received.Value(ctxKey{}) != "value" {
+		t.Error("Expected provider to receive the caller's context")
+	}
+
+	// 旧版 ProviderFunc 注册依然可用
+	factory.RegisterProvider("mock", func(config Config) (Embedder, error) {
+		return &MockEmbedder{}, nil
+	})
+	if _, err := factory.CreateWithConfigContext(ctx, Config{Provider: "mock"}); err != nil {
+		t.Errorf("Legacy provider failed: %v", err)
+	}
+}
+
+func TestBuildContextCancelsOllamaInit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := New("ollama").WithBaseURL(server.URL).BuildContext(ctx)
+	if err == nil {
+		t.Fatal("Expected BuildContext to fail after deadline")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected BuildContext to honor deadline, took %v", elapsed)
+	}
+}
+
 func TestNewBuilder(t *testing.T) {
 	builder := New("test").
 		WithBaseURL("http://test.com").
@@ -172,6 +231,246 @@ func TestOllamaEmbedderWithMockServer(t *testing.T) {
 	}
 }
 
+func TestOllamaEmbedderNativeBatch(t *testing.T) {
+	var batchRequests, legacyRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/version":
+			w.WriteHeader(http.StatusOK)
+		case "/api/embed":
+			batchRequests++
+			var req ollamaBatchRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			resp := ollamaBatchResponse{Model: req.Model}
+			for i := range req.Input {
+				resp.Embeddings = append(resp.Embeddings, []float64{float64(len(req.Input[i])), 0.5, 0.5})
+			}
+			json.NewEncoder(w).Encode(resp)
+		case "/api/embeddings":
+			legacyRequests++
+			w.Write([]byte(`{"embedding": [0.1, 0.2, 0.3]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	embedder, err := NewOllamaEmbedder(Config{BaseURL: server.URL, Model: "test-model", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create OllamaEmbedder: %v", err)
+	}
+	if embedder.GetDimension() != 3 {
+		t.Errorf("Expected dimension 3, got %d", embedder.GetDimension())
+	}
+
+	batchRequests = 0
+	texts := []string{"a", "bb", "ccc", "dddd", "eeeee"}
+	embeddings, err := embedder.BatchEmbed(context.Background(), texts, 2)
+	if err != nil {
+		t.Fatalf("BatchEmbed failed: %v", err)
+	}
+	if batchRequests != 3 {
+		t.Errorf("Expected 3 batch requests, got %d", batchRequests)
+	}
+	if legacyRequests != 0 {
+		t.Errorf("Expected no legacy requests, got %d", legacyRequests)
+	}
+	for i, embedding := range embeddings {
+		if int(embedding[0]) != len(texts[i]) {
+			t.Errorf("Embedding %d out of order: got %v", i, embedding)
+		}
+	}
+}
+
+func TestOllamaEmbedderModelNotFoundDoesNotFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "model 'missing' not found"}`))
+	}))
+	defer server.Close()
+
+	embedder := &OllamaEmbedder{baseURL: server.URL, model: "missing", httpClient: server.Client(), logger: NewLogger("test")}
+	if _, err := embedder.Embed(context.Background(), []string{"text"}); err == nil {
+		t.Fatal("Expected error for missing model")
+	}
+	if embedder.legacy.Load() {
+		t.Error("Model-not-found error should not switch to the legacy endpoint")
+	}
+}
+
+func TestOllamaEmbedderConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embeddings" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		var req ollamaEmbedRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Prompt == "fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(ollamaEmbedResponse{Embedding: []float64{float64(len(req.Prompt))}})
+	}))
+	defer server.Close()
+
+	embedder := &OllamaEmbedder{
+		baseURL:     server.URL,
+		model:       "test-model",
+		httpClient:  server.Client(),
+		concurrency: 4,
+		logger:      NewLogger("test"),
+	}
+
+	texts := make([]string, 12)
+	for i := range texts {
+		texts[i] = strings.Repeat("x", i+1)
+	}
+	embeddings, err := embedder.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	for i, embedding := range embeddings {
+		if int(embedding[0]) != i+1 {
+			t.Errorf("Embedding %d out of order: got %v", i, embedding)
+		}
+	}
+	if maxInFlight < 2 || maxInFlight > 4 {
+		t.Errorf("Expected between 2 and 4 parallel requests, got %d", maxInFlight)
+	}
+
+	texts[3] = "fail"
+	if _, err := embedder.Embed(context.Background(), texts); err == nil || !strings.Contains(err.Error(), "index 3") {
+		t.Errorf("Expected error at index 3, got %v", err)
+	}
+}
+
+func TestOllamaEmbedderLegacyBatchConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embeddings" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		json.NewEncoder(w).Encode(ollamaEmbedResponse{Embedding: []float64{1}})
+	}))
+	defer server.Close()
+
+	embedder, err := NewOllamaEmbedder(Config{
+		BaseURL: server.URL,
+		Model:   "test-model",
+		Options: map[string]interface{}{OptionConcurrency: 4, OptionLazy: true, OptionDimension: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	texts := numbers(64)
+	// 批次并发与旧版接口的逐个并发共用上限，不会达到 concurrency²
+	if _, err := embedder.BatchEmbed(context.Background(), texts, 8); err != nil {
+		t.Fatalf("BatchEmbed failed: %v", err)
+	}
+	if peak := atomic.LoadInt32(&maxInFlight); peak > 4 {
+		t.Errorf("Expected at most 4 concurrent requests, got %d", peak)
+	}
+}
+
+func TestOllamaEmbedderLazyInit(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/api/version":
+			w.WriteHeader(http.StatusOK)
+		case "/api/embed":
+			w.Write([]byte(`{"embeddings": [[0.1, 0.2, 0.3, 0.4, 0.5]]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	config := Config{
+		BaseURL: server.URL,
+		Model:   "test-model",
+		Timeout: 5 * time.Second,
+		Options: map[string]interface{}{OptionLazy: true},
+	}
+
+	embedder, err := NewOllamaEmbedder(config)
+	if err != nil {
+		t.Fatalf("Failed to create lazy OllamaEmbedder: %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("Expected no requests during lazy construction, got %d", requests)
+	}
+	if embedder.GetDimension() != 0 {
+		t.Errorf("Expected unknown dimension before first call, got %d", embedder.GetDimension())
+	}
+
+	if _, err := embedder.EmbedSingle(context.Background(), "text"); err != nil {
+		t.Fatalf("EmbedSingle failed: %v", err)
+	}
+	if embedder.GetDimension() != 5 {
+		t.Errorf("Expected dimension 5 after first call, got %d", embedder.GetDimension())
+	}
+
+	// 显式 Init 并且已知维度时只做健康检查
+	config.Options[OptionDimension] = 768
+	embedder, _ = NewOllamaEmbedder(config)
+	atomic.StoreInt32(&requests, 0)
+	if err := embedder.Init(context.Background()); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if requests != 1 || embedder.GetDimension() != 768 {
+		t.Errorf("Expected only a health check and dimension 768, got %d requests and dimension %d", requests, embedder.GetDimension())
+	}
+}
+
+func TestOllamaEmbedderLazyInitServerDown(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	embedder, err := NewOllamaEmbedder(Config{
+		BaseURL: server.URL,
+		Model:   "test-model",
+		Timeout: time.Second,
+		Options: map[string]interface{}{OptionLazy: "true"},
+	})
+	if err != nil {
+		t.Fatalf("Lazy construction should not touch the network: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := embedder.Init(ctx); err == nil {
+		t.Error("Expected Init to fail with cancelled context")
+	}
+}
+
 func TestCreateEmbedder(t *testing.T) {
 	// 测试不存在的provider
 	_, err := CreateEmbedder("nonexistent")
@@ -216,4 +515,37 @@ func (m *MockEmbedder) GetModel() string {
 
 func (m *MockEmbedder) Health(ctx context.Context) error {
 	return nil
-}
\ No newline at end of file
+}
+func TestOllamaEmbedderVerifiesDimension(t *testing.T) {
+	var response atomic.Value
+	response.Store(`{"embeddings": [[0.1, 0.2, 0.3, 0.4, 0.5]]}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(response.Load().(string)))
+	}))
+	defer server.Close()
+
+	// 注册表或选项中的维度与实际不一致时，按首次响应更正
+	embedder, err := NewOllamaEmbedder(Config{
+		BaseURL: server.URL,
+		Model:   "test-model",
+		Options: map[string]interface{}{OptionLazy: true, OptionDimension: 768},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := embedder.EmbedSingle(context.Background(), "text"); err != nil {
+		t.Fatalf("EmbedSingle failed: %v", err)
+	}
+	if embedder.GetDimension() != 5 {
+		t.Errorf("Expected dimension corrected to 5, got %d", embedder.GetDimension())
+	}
+
+	// 之后维度变化说明服务端模型已改变
+	response.Store(`{"embeddings": [[0.1, 0.2, 0.3]]}`)
+	if _, err := embedder.EmbedSingle(context.Background(), "text"); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("Expected ErrInvalidResponse for changed dimension, got %v", err)
+	}
+	if embedder.GetDimension() != 5 {
+		t.Errorf("Expected dimension to stay 5, got %d", embedder.GetDimension())
+	}
+}
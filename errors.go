@@ -0,0 +1,171 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 错误分类，可通过 errors.Is 判断
+var (
+	// ErrProviderNotFound 未注册的provider
+	ErrProviderNotFound = errors.New("provider not found")
+	// ErrProviderExists provider已注册
+	ErrProviderExists = errors.New("provider already registered")
+	// ErrInvalidConfig 配置无效
+	ErrInvalidConfig = errors.New("invalid config")
+	// ErrModelNotFound 服务端不存在该模型
+	ErrModelNotFound = errors.New("model not found")
+	// ErrInputTooLong 输入超过模型上下文长度
+	ErrInputTooLong = errors.New("input too long")
+	// ErrRateLimited 请求被限流（HTTP 429）
+	ErrRateLimited = errors.New("rate limited")
+	// ErrUnauthorized 认证失败（HTTP 401/403）
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrUnavailable 服务不可用：连接失败、超时或 HTTP 5xx
+	ErrUnavailable = errors.New("service unavailable")
+	// ErrInvalidResponse 服务端响应格式不符合预期
+	ErrInvalidResponse = errors.New("invalid response")
+)
+
+// ProviderError provider请求失败的详细错误
+// 通过 errors.As 获取状态码、provider、模型和输入下标，通过 errors.Is 匹配分类
+type ProviderError struct {
+	// Provider provider名称，如 ollama、openai
+	Provider string
+	// Model 请求的模型名称
+	Model string
+	// StatusCode HTTP状态码，网络错误时为 0
+	StatusCode int
+	// Index 出错的输入下标，-1 表示整批请求
+	Index int
+	// Message 服务端返回的错误内容
+	Message string
+	// Kind 错误分类（ErrModelNotFound 等），无法分类时为 nil
+	Kind error
+	// Err 底层错误，如网络错误
+	Err error
+
+	retryAfter time.Duration
+}
+
+func (e *ProviderError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Provider)
+	if e.Model != "" {
+		fmt.Fprintf(&b, " (model %s)", e.Model)
+	}
+	if e.Index >= 0 {
+		fmt.Fprintf(&b, " input %d", e.Index)
+	}
+	b.WriteString(": ")
+
+	switch {
+	case e.StatusCode > 0:
+		fmt.Fprintf(&b, "HTTP %d: %s", e.StatusCode, e.Message)
+	case e.Err != nil:
+		b.WriteString(e.Err.Error())
+	case e.Kind != nil:
+		b.WriteString(e.Kind.Error())
+	default:
+		b.WriteString(e.Message)
+	}
+	return b.String()
+}
+
+// Unwrap 同时暴露错误分类和底层错误
+func (e *ProviderError) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// HTTPStatusCode 返回HTTP状态码，供重试策略判断
+func (e *ProviderError) HTTPStatusCode() int {
+	return e.StatusCode
+}
+
+// RetryAfter 返回服务端建议的等待时间
+func (e *ProviderError) RetryAfter() time.Duration {
+	return e.retryAfter
+}
+
+// newStatusError 根据非2xx响应创建错误并分类（读取响应体）
+func newStatusError(provider, model string, resp *http.Response) *ProviderError {
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	body := string(bodyBytes)
+
+	return &ProviderError{
+		Provider:   provider,
+		Model:      model,
+		StatusCode: resp.StatusCode,
+		Index:      -1,
+		Message:    body,
+		Kind:       classifyStatus(resp.StatusCode, body),
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+}
+
+// newTransportError 包装连接失败、超时等网络错误
+// 调用方 ctx 取消时不归为 ErrUnavailable，可直接用 errors.Is 判断 context 错误
+func newTransportError(ctx context.Context, provider, model string, err error) *ProviderError {
+	providerErr := &ProviderError{
+		Provider: provider,
+		Model:    model,
+		Index:    -1,
+		Err:      err,
+	}
+	if ctx.Err() == nil {
+		providerErr.Kind = ErrUnavailable
+	}
+	return providerErr
+}
+
+// newDecodeError 包装响应解析失败
+func newDecodeError(err error) error {
+	return fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+}
+
+// withIndex 为错误链中的 ProviderError 记录出错的输入下标
+func withIndex(err error, index int) error {
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		providerErr.Index = index
+	}
+	return err
+}
+
+// classifyStatus 根据状态码和响应内容判断错误分类
+func classifyStatus(status int, body string) error {
+	lower := strings.ToLower(body)
+
+	// 上下文超长在各服务端的状态码不一致（400/413/500），优先按内容判断
+	if status == http.StatusRequestEntityTooLarge ||
+		strings.Contains(lower, "context length") ||
+		strings.Contains(lower, "too long") ||
+		strings.Contains(lower, "too many tokens") {
+		return ErrInputTooLong
+	}
+
+	switch {
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrUnauthorized
+	case strings.Contains(lower, "model") &&
+		(strings.Contains(lower, "not found") || strings.Contains(lower, "does not exist")):
+		return ErrModelNotFound
+	case status >= 500:
+		return ErrUnavailable
+	}
+	return nil
+}
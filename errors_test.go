@@ -0,0 +1,112 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClassifyStatus(t *testing.T) {
+	cases := []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{http.StatusNotFound, `{"error":"model \"missing\" not found, try pulling it first"}`, ErrModelNotFound},
+		{http.StatusNotFound, `{"error":{"message":"The model 'x' does not exist"}}`, ErrModelNotFound},
+		{http.StatusBadRequest, `{"error":"input length exceeds the context length"}`, ErrInputTooLong},
+		{http.StatusRequestEntityTooLarge, "", ErrInputTooLong},
+		{http.StatusTooManyRequests, "", ErrRateLimited},
+		{http.StatusUnauthorized, "", ErrUnauthorized},
+		{http.StatusBadGateway, "", ErrUnavailable},
+		{http.StatusBadRequest, `{"error":"bad request"}`, nil},
+	}
+	for _, c := range cases {
+		if got := classifyStatus(c.status, c.body); got != c.want {
+			t.Errorf("classifyStatus(%d, %q) = %v, want %v", c.status, c.body, got, c.want)
+		}
+	}
+}
+
+func TestOllamaEmbedderTypedErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/version":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/api/embed":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "model \"missing\" not found, try pulling it first"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	embedder := &OllamaEmbedder{baseURL: server.URL, model: "missing", httpClient: server.Client(), logger: NewLogger("test")}
+
+	_, err := embedder.Embed(context.Background(), []string{"text"})
+	if !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("Expected ErrModelNotFound, got %v", err)
+	}
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) {
+		t.Fatalf("Expected *ProviderError, got %T", err)
+	}
+	if providerErr.Provider != "ollama" || providerErr.Model != "missing" || providerErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Unexpected error details: %+v", providerErr)
+	}
+
+	if err := embedder.Health(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable from Health, got %v", err)
+	}
+}
+
+func TestOllamaEmbedderErrorIndex(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "input length exceeds the context length"}`))
+	}))
+	defer server.Close()
+
+	embedder := &OllamaEmbedder{baseURL: server.URL, model: "test-model", httpClient: server.Client(), logger: NewLogger("test")}
+	embedder.legacy.Store(true)
+
+	_, err := embedder.Embed(context.Background(), []string{"too long"})
+	if !errors.Is(err, ErrInputTooLong) {
+		t.Errorf("Expected ErrInputTooLong, got %v", err)
+	}
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || providerErr.Index != 0 {
+		t.Errorf("Expected error at input 0, got %v", err)
+	}
+}
+
+func TestTransportErrorIsUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	embedder := &OllamaEmbedder{baseURL: server.URL, model: "test-model", httpClient: &http.Client{Timeout: time.Second}, logger: NewLogger("test")}
+	if err := embedder.Health(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable for closed server, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := embedder.Health(ctx)
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected context.Canceled without ErrUnavailable, got %v", err)
+	}
+}
+
+func TestFactoryTypedErrors(t *testing.T) {
+	factory := NewFactory()
+	if _, err := factory.Create("nonexistent"); !errors.Is(err, ErrProviderNotFound) {
+		t.Errorf("Expected ErrProviderNotFound, got %v", err)
+	}
+	if err := factory.RegisterProvider("ollama", nil); !errors.Is(err, ErrProviderExists) {
+		t.Errorf("Expected ErrProviderExists, got %v", err)
+	}
+}
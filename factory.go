@@ -1,27 +1,35 @@
 package embedder
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"sync"
 )
 
 // Factory 嵌入服务工厂
 type Factory struct {
-	providers map[string]ProviderFunc
+	providers map[string]ProviderFuncCtx
 	mu        sync.RWMutex
 	logger    *Logger
+	slog      *slog.Logger
 }
 
 // NewFactory 创建新的工厂实例
 func NewFactory() *Factory {
 	factory := &Factory{
-		providers: make(map[string]ProviderFunc),
+		providers: make(map[string]ProviderFuncCtx),
 		logger:    NewLogger("embedder-factory"),
 	}
 	
 	// 注册默认的 Ollama provider
-	factory.RegisterProvider("ollama", func(config Config) (Embedder, error) {
-		return NewOllamaEmbedder(config)
+	factory.RegisterProviderContext("ollama", func(ctx context.Context, config Config) (Embedder, error) {
+		return NewOllamaEmbedderContext(ctx, config)
+	})
+	
+	// 注册默认的 OpenAI 兼容 provider
+	factory.RegisterProviderContext("openai", func(ctx context.Context, config Config) (Embedder, error) {
+		return NewOpenAIEmbedderContext(ctx, config)
 	})
 	
 	return factory
@@ -29,45 +37,146 @@ func NewFactory() *Factory {
 
 // Create 根据provider名称创建嵌入服务
 func (f *Factory) Create(provider string) (Embedder, error) {
+	return f.CreateContext(context.Background(), provider)
+}
+
+// CreateContext 根据provider名称创建嵌入服务，ctx 控制构造过程中的网络请求
+func (f *Factory) CreateContext(ctx context.Context, provider string) (Embedder, error) {
 	f.mu.RLock()
 	providerFunc, exists := f.providers[provider]
 	f.mu.RUnlock()
 	
 	if !exists {
-		return nil, fmt.Errorf("unsupported provider: %s", provider)
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, provider)
 	}
 	
 	// 使用默认配置，但设置正确的provider
 	config := DefaultConfig
 	config.Provider = provider
+	config.Logger = f.getSlog()
 	
-	f.logger.Info("创建嵌入服务", String("provider", provider))
-	return providerFunc(config)
+	f.getLogger().InfoContext(ctx, "创建嵌入服务", String("provider", provider))
+	return providerFunc(ctx, config)
 }
 
 // CreateWithConfig 使用指定配置创建嵌入服务
 func (f *Factory) CreateWithConfig(config Config) (Embedder, error) {
+	return f.CreateWithConfigContext(context.Background(), config)
+}
+
+// CreateWithConfigContext 使用指定配置创建嵌入服务，ctx 控制构造过程中的网络请求
+func (f *Factory) CreateWithConfigContext(ctx context.Context, config Config) (Embedder, error) {
 	f.mu.RLock()
 	providerFunc, exists := f.providers[config.Provider]
 	f.mu.RUnlock()
 	
 	if !exists {
-		return nil, fmt.Errorf("unsupported provider: %s", config.Provider)
+		return nil, fmt.Errorf("%w: %s", ErrProviderNotFound, config.Provider)
+	}
+	
+	if err := config.Overflow.Validate(); err != nil {
+		return nil, err
+	}
+	if config.Logger == nil {
+		config.Logger = f.getSlog()
 	}
 	
-	f.logger.Info("创建嵌入服务", 
+	f.getLogger().InfoContext(ctx, "创建嵌入服务", 
 		String("provider", config.Provider),
 		String("model", config.Model))
-	return providerFunc(config)
+	embedder, err := f.createBackends(ctx, providerFunc, config)
+	if err != nil {
+		return nil, err
+	}
+	
+	// 模型需要查询/文档前缀时提供 EmbedQuery / EmbedDocuments
+	if template := resolvePromptTemplate(config); !template.IsZero() {
+		embedder = NewInstructionEmbedder(embedder, template)
+	}
+	// 设置了长度限制时统一处理超长输入，对所有provider生效
+	// 包在前缀之外：前缀计入长度限制，截断或切分后的每一段都带有前缀
+	overflow := config.Overflow
+	if overflow.Mode != "" && !overflow.Enabled() {
+		if info, ok := ModelInfo(config.Model); ok {
+			overflow.MaxTokens = info.MaxTokens
+		}
+	}
+	if overflow.Enabled() {
+		overflowEmbedder := NewOverflowEmbedder(embedder, overflow)
+		overflowEmbedder.logger = NewSlogLogger(config.Logger, "overflow-embedder")
+		embedder = overflowEmbedder
+	}
+	// 追踪放在最外层，span 覆盖前缀、截断等全部处理
+	if config.Tracer != nil {
+		embedder = NewTracingEmbedder(embedder, config.Tracer, config.Provider)
+	}
+	return embedder, nil
+}
+
+// createBackends 创建 provider 实例，设置了 BaseURLs 时为每个地址创建一个并负载均衡（私有方法）
+func (f *Factory) createBackends(ctx context.Context, providerFunc ProviderFuncCtx, config Config) (Embedder, error) {
+	if err := config.Limit.Validate(); err != nil {
+		return nil, err
+	}
+	if err := config.Circuit.Validate(); err != nil {
+		return nil, err
+	}
+	if len(config.BaseURLs) == 0 {
+		return f.createBackend(ctx, providerFunc, config)
+	}
+	if err := config.Balance.Validate(len(config.BaseURLs)); err != nil {
+		return nil, err
+	}
+	
+	backends := make([]Embedder, 0, len(config.BaseURLs))
+	for _, baseURL := range config.BaseURLs {
+		backendConfig := config
+		backendConfig.BaseURL = baseURL
+		backendConfig.BaseURLs = nil
+		backend, err := f.createBackend(ctx, providerFunc, backendConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create backend %s: %w", baseURL, err)
+		}
+		backends = append(backends, backend)
+	}
+	return NewLoadBalancedEmbedder(backends, config.Balance)
+}
+
+// createBackend 创建单个 provider 实例，按配置包装限流和熔断（私有方法）
+func (f *Factory) createBackend(ctx context.Context, providerFunc ProviderFuncCtx, config Config) (Embedder, error) {
+	embedder, err := providerFunc(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	// 限流在熔断内层，熔断期间的请求不占用配额
+	if config.Limit.Enabled() {
+		embedder = NewLimitEmbedder(embedder, config.Limit)
+	}
+	if config.Circuit.Enabled() {
+		breaker := NewCircuitBreaker(embedder, config.Circuit)
+		breaker.logger = NewSlogLogger(config.Logger, "circuit-breaker")
+		embedder = breaker
+	}
+	return embedder, nil
 }
 
 // RegisterProvider 注册新的provider
 func (f *Factory) RegisterProvider(name string, provider ProviderFunc) error {
+	if provider == nil {
+		return f.RegisterProviderContext(name, nil)
+	}
+	return f.RegisterProviderContext(name, func(ctx context.Context, config Config) (Embedder, error) {
+		return provider(config)
+	})
+}
+
+// RegisterProviderContext 注册支持context的provider
+func (f *Factory) RegisterProviderContext(name string, provider ProviderFuncCtx) error {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 	
 	if _, exists := f.providers[name]; exists {
-		return fmt.Errorf("provider %s already registered", name)
+		return fmt.Errorf("%w: %s", ErrProviderExists, name)
 	}
 	
 	f.providers[name] = provider
@@ -75,6 +184,30 @@ func (f *Factory) RegisterProvider(name string, provider ProviderFunc) error {
 	return nil
 }
 
+// SetLogger 设置工厂及其创建的嵌入服务使用的日志，Config.Logger 优先
+// 传入 nil 时使用包级默认日志
+func (f *Factory) SetLogger(logger *slog.Logger) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	
+	f.slog = logger
+	f.logger = NewSlogLogger(logger, "embedder-factory")
+}
+
+// getSlog 读取工厂的日志配置（私有方法）
+func (f *Factory) getSlog() *slog.Logger {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.slog
+}
+
+// getLogger 读取工厂自身的日志记录器（私有方法）
+func (f *Factory) getLogger() *Logger {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.logger
+}
+
 // ListProviders 列出所有可用的provider
 func (f *Factory) ListProviders() []string {
 	f.mu.RLock()
@@ -100,6 +233,7 @@ func New(provider string) *EmbedderBuilder {
 				Model:    DefaultConfig.Model,
 				Timeout:  DefaultConfig.Timeout,
 				Options:  make(map[string]interface{}),
+				Retry:    DefaultConfig.Retry,
 			},
 		},
 		factory: defaultFactory,
@@ -118,6 +252,12 @@ func (b *EmbedderBuilder) WithBaseURL(baseURL string) *EmbedderBuilder {
 	return b
 }
 
+// WithBaseURLs 设置多个服务地址，请求按策略负载均衡到各地址
+func (b *EmbedderBuilder) WithBaseURLs(baseURLs []string, policy BalancePolicy) *EmbedderBuilder {
+	b.config.WithBaseURLs(baseURLs, policy)
+	return b
+}
+
 // WithModel 设置模型名称
 func (b *EmbedderBuilder) WithModel(model string) *EmbedderBuilder {
 	b.config.WithModel(model)
@@ -130,6 +270,42 @@ func (b *EmbedderBuilder) WithTimeout(timeout interface{}) *EmbedderBuilder {
 	return b
 }
 
+// WithRetry 设置重试策略
+func (b *EmbedderBuilder) WithRetry(policy RetryPolicy) *EmbedderBuilder {
+	b.config.WithRetry(policy)
+	return b
+}
+
+// WithOverflow 设置超长输入策略
+func (b *EmbedderBuilder) WithOverflow(policy OverflowPolicy) *EmbedderBuilder {
+	b.config.WithOverflow(policy)
+	return b
+}
+
+// WithCircuit 设置熔断策略，后端持续失败时直接返回 CircuitOpenError
+func (b *EmbedderBuilder) WithCircuit(policy CircuitPolicy) *EmbedderBuilder {
+	b.config.WithCircuit(policy)
+	return b
+}
+
+// WithLimit 设置客户端限流策略
+func (b *EmbedderBuilder) WithLimit(policy LimitPolicy) *EmbedderBuilder {
+	b.config.WithLimit(policy)
+	return b
+}
+
+// WithLogger 设置日志，默认使用包级默认日志（静默）
+func (b *EmbedderBuilder) WithLogger(logger *slog.Logger) *EmbedderBuilder {
+	b.config.WithLogger(logger)
+	return b
+}
+
+// WithTracer 设置链路追踪钩子，嵌入调用和每次HTTP请求都会记录 span
+func (b *EmbedderBuilder) WithTracer(tracer Tracer) *EmbedderBuilder {
+	b.config.WithTracer(tracer)
+	return b
+}
+
 // WithOption 设置自定义选项
 func (b *EmbedderBuilder) WithOption(key string, value interface{}) *EmbedderBuilder {
 	b.config.WithOption(key, value)
@@ -146,6 +322,11 @@ func (b *EmbedderBuilder) Build() (Embedder, error) {
 	return b.factory.CreateWithConfig(b.config.GetConfig())
 }
 
+// BuildContext 构建嵌入服务，ctx 控制构造过程中的网络请求
+func (b *EmbedderBuilder) BuildContext(ctx context.Context) (Embedder, error) {
+	return b.factory.CreateWithConfigContext(ctx, b.config.GetConfig())
+}
+
 // CreateEmbedder 直接创建嵌入服务的便捷方法
 func CreateEmbedder(provider string) (Embedder, error) {
 	return defaultFactory.Create(provider)
@@ -156,11 +337,21 @@ func CreateEmbedderWithConfig(config Config) (Embedder, error) {
 	return defaultFactory.CreateWithConfig(config)
 }
 
+// CreateEmbedderWithConfigContext 使用配置和context创建嵌入服务的便捷方法
+func CreateEmbedderWithConfigContext(ctx context.Context, config Config) (Embedder, error) {
+	return defaultFactory.CreateWithConfigContext(ctx, config)
+}
+
 // RegisterProvider 注册provider的便捷方法
 func RegisterProvider(name string, provider ProviderFunc) error {
 	return defaultFactory.RegisterProvider(name, provider)
 }
 
+// RegisterProviderContext 注册支持context的provider的便捷方法
+func RegisterProviderContext(name string, provider ProviderFuncCtx) error {
+	return defaultFactory.RegisterProviderContext(name, provider)
+}
+
 // ListProviders 列出providers的便捷方法
 func ListProviders() []string {
 	return defaultFactory.ListProviders()
@@ -0,0 +1,340 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FailoverPolicy 故障转移策略，零值字段使用 DefaultFailoverPolicy 中的值
+type FailoverPolicy struct {
+	// FailureThreshold 连续失败多少次后熔断该后端
+	FailureThreshold int `yaml:"failure_threshold"`
+	// Cooldown 熔断后多久放行一个试探请求
+	Cooldown time.Duration `yaml:"cooldown"`
+	// ProbeInterval 后台健康检查间隔，<=0 时不启动后台检查
+	// 检查失败的后端立即熔断，熔断中的后端检查通过后立即恢复
+	ProbeInterval time.Duration `yaml:"probe_interval"`
+
+	// OnStateChange 后端熔断状态变化时的回调，backend 为后端下标
+	OnStateChange func(backend int, from, to CircuitState) `yaml:"-"`
+}
+
+// DefaultFailoverPolicy 默认故障转移策略
+var DefaultFailoverPolicy = FailoverPolicy{
+	FailureThreshold: 3,
+	Cooldown:         30 * time.Second,
+}
+
+// FailoverEmbedder 按顺序使用多个后端的故障转移嵌入服务
+// 优先使用排在前面的后端，失败时转到下一个；每个后端独立维护熔断状态
+type FailoverEmbedder struct {
+	backends  []*failoverBackend
+	policy    FailoverPolicy
+	dimension *dimensionGuard
+	logger    *Logger
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
+}
+
+// failoverBackend 单个后端及其熔断状态
+type failoverBackend struct {
+	embedder Embedder
+	circuit  *circuit
+}
+
+// NewFailoverEmbedder 创建故障转移嵌入服务，backends 按优先级排列
+// 所有后端必须使用相同的模型和维度；未知维度的延迟初始化后端在返回向量后检查，维度不一致时返回 ErrInvalidResponse
+// 设置了 ProbeInterval 时会启动后台健康检查，不再使用时需调用 Close
+func NewFailoverEmbedder(backends []Embedder, policy FailoverPolicy) (*FailoverEmbedder, error) {
+	dimension, err := checkBackends(backends)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy.FailureThreshold <= 0 {
+		policy.FailureThreshold = DefaultFailoverPolicy.FailureThreshold
+	}
+	if policy.Cooldown <= 0 {
+		policy.Cooldown = DefaultFailoverPolicy.Cooldown
+	}
+
+	f := &FailoverEmbedder{
+		policy:    policy,
+		dimension: newDimensionGuard(dimension),
+		logger:    NewLogger("failover-embedder"),
+		stop:      make(chan struct{}),
+	}
+	circuitPolicy := CircuitPolicy{ConsecutiveFailures: policy.FailureThreshold, Cooldown: policy.Cooldown}
+	for i, backend := range backends {
+		i := i
+		f.backends = append(f.backends, &failoverBackend{
+			embedder: backend,
+			circuit: newCircuit(circuitPolicy, func(from, to CircuitState, err error) {
+				f.notify(i, from, to, err)
+			}),
+		})
+	}
+
+	if policy.ProbeInterval > 0 {
+		f.wg.Add(1)
+		go f.probeLoop()
+	}
+	return f, nil
+}
+
+// Embed 批量嵌入多个文本
+func (f *FailoverEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var result [][]float32
+	err := f.do(ctx, func(e Embedder) error {
+		var err error
+		if result, err = e.Embed(ctx, texts); err != nil {
+			return err
+		}
+		return f.dimension.check(result...)
+	})
+	return result, err
+}
+
+// EmbedSingle 嵌入单个文本
+func (f *FailoverEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	var result []float32
+	err := f.do(ctx, func(e Embedder) error {
+		var err error
+		if result, err = e.EmbedSingle(ctx, text); err != nil {
+			return err
+		}
+		return f.dimension.check(result)
+	})
+	return result, err
+}
+
+// BatchEmbed 分批处理大量文本，每批独立故障转移
+func (f *FailoverEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	if batchSize <= 0 {
+		batchSize = len(texts)
+	}
+
+	var allEmbeddings [][]float32
+	for i := 0; i < len(texts); i += batchSize {
+		end := i + batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		embeddings, err := f.Embed(ctx, texts[i:end])
+		if err != nil {
+			return nil, err
+		}
+
+		allEmbeddings = append(allEmbeddings, embeddings...)
+	}
+
+	return allEmbeddings, nil
+}
+
+// EmbedQuery 以查询模式嵌入文本，后端不支持时直接嵌入
+func (f *FailoverEmbedder) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	var result []float32
+	err := f.do(ctx, func(e Embedder) error {
+		var err error
+		if result, err = EmbedQuery(ctx, e, query); err != nil {
+			return err
+		}
+		return f.dimension.check(result)
+	})
+	return result, err
+}
+
+// EmbedDocuments 以文档模式嵌入多个文本，后端不支持时直接嵌入
+func (f *FailoverEmbedder) EmbedDocuments(ctx context.Context, documents []string) ([][]float32, error) {
+	var result [][]float32
+	err := f.do(ctx, func(e Embedder) error {
+		var err error
+		if result, err = EmbedDocuments(ctx, e, documents); err != nil {
+			return err
+		}
+		return f.dimension.check(result...)
+	})
+	return result, err
+}
+
+// GetDimension 获取嵌入维度，取第一个已知维度的后端
+func (f *FailoverEmbedder) GetDimension() int {
+	for _, b := range f.backends {
+		if d := b.embedder.GetDimension(); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// GetModel 获取模型名称
+func (f *FailoverEmbedder) GetModel() string {
+	return f.backends[0].embedder.GetModel()
+}
+
+// Health 检查所有后端并更新熔断状态，任一后端健康即返回 nil
+func (f *FailoverEmbedder) Health(ctx context.Context) error {
+	var lastErr error
+	healthy := false
+	for i := range f.backends {
+		if err := f.check(ctx, i); err != nil {
+			lastErr = err
+		} else {
+			healthy = true
+		}
+	}
+	if healthy {
+		return nil
+	}
+	return fmt.Errorf("%w: no healthy backend: %w", ErrUnavailable, lastErr)
+}
+
+// State 返回第 i 个后端的熔断状态
+func (f *FailoverEmbedder) State(i int) CircuitState {
+	return f.backends[i].circuit.State()
+}
+
+// Close 停止后台健康检查，不关闭后端
+func (f *FailoverEmbedder) Close() error {
+	f.once.Do(func() { close(f.stop) })
+	f.wg.Wait()
+	return nil
+}
+
+// do 按优先级依次尝试可用后端，直到成功或遇到不应转移的错误（私有方法）
+func (f *FailoverEmbedder) do(ctx context.Context, fn func(Embedder) error) error {
+	var lastErr error
+	for _, b := range f.backends {
+		ticket, err := b.circuit.acquire()
+		if err != nil {
+			continue
+		}
+
+		err = fn(b.embedder)
+		b.circuit.done(ctx, ticket, err)
+		switch {
+		case err == nil:
+			return nil
+		case ctx.Err() != nil, !backendFailure(err):
+			// 调用方取消或输入错误，换后端也不会成功
+			return err
+		}
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		return fmt.Errorf("%w: all %d backends are unavailable", ErrUnavailable, len(f.backends))
+	}
+	return fmt.Errorf("%w: all backends failed: %w", ErrUnavailable, lastErr)
+}
+
+// check 对单个后端做健康检查并更新状态（私有方法）
+func (f *FailoverEmbedder) check(ctx context.Context, i int) error {
+	b := f.backends[i]
+	err := b.embedder.Health(ctx)
+	if err != nil {
+		// 超时说明后端无响应，只有主动取消时不更新状态
+		if !errors.Is(ctx.Err(), context.Canceled) {
+			b.circuit.trip(err)
+		}
+		return err
+	}
+	b.circuit.reset()
+	return nil
+}
+
+// probeLoop 定期健康检查所有后端（私有方法）
+func (f *FailoverEmbedder) probeLoop() {
+	defer f.wg.Done()
+
+	ticker := time.NewTicker(f.policy.ProbeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-f.stop:
+			return
+		case <-ticker.C:
+		}
+
+		for i := range f.backends {
+			ctx, cancel := context.WithTimeout(context.Background(), f.policy.ProbeInterval)
+			f.check(ctx, i)
+			cancel()
+		}
+	}
+}
+
+// notify 后端状态变化时记录日志并调用 OnStateChange（私有方法）
+func (f *FailoverEmbedder) notify(i int, from, to CircuitState, err error) {
+	if from == to {
+		return
+	}
+
+	fields := []Field{Int("backend", i), String("from", from.String()), String("to", to.String())}
+	if err != nil {
+		fields = append(fields, Error(err))
+	}
+	if to == CircuitOpen {
+		f.logger.Warn("后端已熔断", fields...)
+	} else {
+		f.logger.Info("后端状态变化", fields...)
+	}
+
+	if f.policy.OnStateChange != nil {
+		f.policy.OnStateChange(i, from, to)
+	}
+}
+
+// checkBackends 检查多个后端能否组合使用：至少一个，且模型和已知维度一致
+// 返回第一个已知的维度，都未知时返回 0
+func checkBackends(backends []Embedder) (int, error) {
+	if len(backends) == 0 {
+		return 0, fmt.Errorf("%w: at least one backend is required", ErrInvalidConfig)
+	}
+
+	model, dimension := backends[0].GetModel(), 0
+	for i, backend := range backends {
+		if backend.GetModel() != model {
+			return 0, fmt.Errorf("%w: backend %d uses model %s, expected %s", ErrInvalidConfig, i, backend.GetModel(), model)
+		}
+		if d := backend.GetDimension(); d > 0 {
+			if dimension > 0 && d != dimension {
+				return 0, fmt.Errorf("%w: backend %d has dimension %d, expected %d", ErrInvalidConfig, i, d, dimension)
+			}
+			dimension = d
+		}
+	}
+	return dimension, nil
+}
+
+// dimensionGuard 检查多个后端返回的向量维度是否一致
+// 创建时未知维度的延迟初始化后端无法预先检查，以第一次返回的向量长度为准
+type dimensionGuard struct {
+	dimension atomic.Int64
+}
+
+// newDimensionGuard 创建维度检查，dimension 为 0 表示尚未知道
+func newDimensionGuard(dimension int) *dimensionGuard {
+	g := &dimensionGuard{}
+	g.dimension.Store(int64(dimension))
+	return g
+}
+
+// check 检查向量维度，与已知维度不一致时返回 ErrInvalidResponse
+func (g *dimensionGuard) check(embeddings ...[]float32) error {
+	for _, vector := range embeddings {
+		g.dimension.CompareAndSwap(0, int64(len(vector)))
+		if want := int(g.dimension.Load()); len(vector) != want {
+			return fmt.Errorf("%w: backend returned dimension %d, expected %d", ErrInvalidResponse, len(vector), want)
+		}
+	}
+	return nil
+}
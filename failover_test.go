@@ -0,0 +1,258 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// backendEmbedder 可切换故障的测试后端
+type backendEmbedder struct {
+	MockEmbedder
+	model     string
+	dimension int
+
+	mu        sync.Mutex
+	err       error
+	healthErr error
+	calls     int
+}
+
+func (b *backendEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls++
+	if b.err != nil {
+		return nil, b.err
+	}
+	return b.MockEmbedder.Embed(ctx, texts)
+}
+
+func (b *backendEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := b.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+func (b *backendEmbedder) GetModel() string {
+	if b.model == "" {
+		return b.MockEmbedder.GetModel()
+	}
+	return b.model
+}
+
+func (b *backendEmbedder) GetDimension() int {
+	return b.dimension
+}
+
+func (b *backendEmbedder) Health(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.healthErr
+}
+
+// set 切换后端故障
+func (b *backendEmbedder) set(err, healthErr error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.err = err
+	b.healthErr = healthErr
+}
+
+// callCount 读取调用次数
+func (b *backendEmbedder) callCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls
+}
+
+func TestFailoverRejectsMismatchedBackends(t *testing.T) {
+	cases := [][]Embedder{
+		{&backendEmbedder{dimension: 3}, &backendEmbedder{model: "other", dimension: 3}},
+		{&backendEmbedder{dimension: 3}, &backendEmbedder{dimension: 4}},
+		nil,
+	}
+	for i, backends := range cases {
+		if _, err := NewFailoverEmbedder(backends, FailoverPolicy{}); !errors.Is(err, ErrInvalidConfig) {
+			t.Errorf("Case %d: expected ErrInvalidConfig, got %v", i, err)
+		}
+	}
+
+	// 延迟初始化的后端维度未知，允许组合
+	f, err := NewFailoverEmbedder([]Embedder{&backendEmbedder{}, &backendEmbedder{dimension: 3}}, FailoverPolicy{})
+	if err != nil {
+		t.Fatalf("Expected unknown dimension to be accepted, got %v", err)
+	}
+	if f.GetDimension() != 3 {
+		t.Errorf("Expected dimension 3, got %d", f.GetDimension())
+	}
+}
+
+func TestFailoverCircuit(t *testing.T) {
+	primary := &backendEmbedder{dimension: 3}
+	secondary := &backendEmbedder{dimension: 3}
+
+	var mu sync.Mutex
+	var transitions []CircuitState
+	f, err := NewFailoverEmbedder([]Embedder{primary, secondary}, FailoverPolicy{
+		FailureThreshold: 2,
+		Cooldown:         20 * time.Millisecond,
+		OnStateChange: func(backend int, from, to CircuitState) {
+			mu.Lock()
+			defer mu.Unlock()
+			if backend == 0 {
+				transitions = append(transitions, to)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	ctx := context.Background()
+
+	primary.set(&ProviderError{Provider: "mock", StatusCode: 503, Index: -1, Kind: ErrUnavailable}, nil)
+	for i := 0; i < 3; i++ {
+		if _, err := f.EmbedSingle(ctx, "hello"); err != nil {
+			t.Fatalf("Expected failover to secondary, got %v", err)
+		}
+	}
+	if primary.callCount() != 2 || secondary.callCount() != 3 {
+		t.Errorf("Expected primary to be skipped after 2 failures, got %d/%d calls", primary.callCount(), secondary.callCount())
+	}
+	if f.State(0) != CircuitOpen {
+		t.Errorf("Expected primary to be open, got %s", f.State(0))
+	}
+
+	// 冷却期后放行试探请求，成功后恢复
+	primary.set(nil, nil)
+	time.Sleep(30 * time.Millisecond)
+	if _, err := f.Embed(ctx, []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if primary.callCount() != 3 || f.State(0) != CircuitClosed {
+		t.Errorf("Expected half-open probe to close the circuit, got %d calls and %s", primary.callCount(), f.State(0))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []CircuitState{CircuitOpen, CircuitHalfOpen, CircuitClosed}
+	if len(transitions) != len(want) {
+		t.Fatalf("Expected transitions %v, got %v", want, transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Errorf("Expected transitions %v, got %v", want, transitions)
+		}
+	}
+}
+
+func TestFailoverErrors(t *testing.T) {
+	primary := &backendEmbedder{dimension: 3}
+	secondary := &backendEmbedder{dimension: 3}
+	f, err := NewFailoverEmbedder([]Embedder{primary, secondary}, FailoverPolicy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// 输入错误不转移
+	primary.set(&ProviderError{Provider: "mock", StatusCode: 400, Index: 0, Kind: ErrInputTooLong}, nil)
+	if _, err := f.Embed(ctx, []string{"a"}); !errors.Is(err, ErrInputTooLong) {
+		t.Errorf("Expected ErrInputTooLong, got %v", err)
+	}
+	if secondary.callCount() != 0 {
+		t.Error("Input errors should not fail over")
+	}
+
+	// 认证失败等与后端状态无关的错误同样不转移
+	primary.set(&ProviderError{Provider: "mock", StatusCode: 401, Index: -1, Kind: ErrUnauthorized}, nil)
+	if _, err := f.Embed(ctx, []string{"a"}); !errors.Is(err, ErrUnauthorized) || secondary.callCount() != 0 {
+		t.Errorf("Expected ErrUnauthorized without failover, got %v", err)
+	}
+
+	primary.set(ErrUnavailable, nil)
+	secondary.set(ErrRateLimited, nil)
+	_, err = f.Embed(ctx, []string{"a"})
+	if !errors.Is(err, ErrUnavailable) || !errors.Is(err, ErrRateLimited) {
+		t.Errorf("Expected ErrUnavailable wrapping last error, got %v", err)
+	}
+}
+
+// lazyEmbedder 创建时不知道维度的延迟初始化后端
+type lazyEmbedder struct {
+	sizedEmbedder
+}
+
+func (l *lazyEmbedder) GetDimension() int {
+	return 0
+}
+
+func TestFailoverChecksRuntimeDimension(t *testing.T) {
+	primary := &backendEmbedder{}
+	secondary := &lazyEmbedder{sizedEmbedder{dimension: 4}}
+	f, err := NewFailoverEmbedder([]Embedder{primary, secondary}, FailoverPolicy{})
+	if err != nil {
+		t.Fatalf("Lazy backends should pass construction, got %v", err)
+	}
+	ctx := context.Background()
+
+	if _, err := f.Embed(ctx, []string{"a"}); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+
+	// 主后端返回 3 维之后，备用后端的 4 维向量不可混用
+	primary.set(ErrUnavailable, nil)
+	if _, err := f.EmbedSingle(ctx, "a"); !errors.Is(err, ErrInvalidResponse) {
+		t.Errorf("Expected ErrInvalidResponse for mismatched dimension, got %v", err)
+	}
+}
+
+func TestFailoverHealthProbe(t *testing.T) {
+	primary := &backendEmbedder{dimension: 3}
+	secondary := &backendEmbedder{dimension: 3}
+	f, err := NewFailoverEmbedder([]Embedder{primary, secondary}, FailoverPolicy{
+		Cooldown:      time.Hour,
+		ProbeInterval: 5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	waitFor := func(want CircuitState) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for f.State(0) != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("Timed out waiting for %s, got %s", want, f.State(0))
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	primary.set(nil, ErrUnavailable)
+	waitFor(CircuitOpen)
+	if _, err := f.Embed(context.Background(), []string{"a"}); err != nil {
+		t.Fatal(err)
+	}
+	if primary.callCount() != 0 {
+		t.Error("Expected unhealthy primary to be skipped")
+	}
+
+	primary.set(nil, nil)
+	waitFor(CircuitClosed)
+
+	secondary.set(nil, ErrUnavailable)
+	if err := f.Health(context.Background()); err != nil {
+		t.Errorf("Expected healthy with one backend up, got %v", err)
+	}
+	primary.set(nil, ErrUnavailable)
+	if err := f.Health(context.Background()); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable, got %v", err)
+	}
+}
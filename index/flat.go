@@ -0,0 +1,91 @@
+package index
+
+import (
+	"github.com/Kizunad/modular-embedder/vector"
+)
+
+// Hit 向量检索结果
+type Hit struct {
+	ID    string
+	Score float32
+}
+
+// VectorIndex 可替换的向量索引结构
+// 实现不需要并发安全，Index 会负责加锁
+type VectorIndex interface {
+	// Add 添加向量，id 已存在时替换
+	Add(id string, vector []float32) error
+
+	// Remove 删除向量，返回是否存在
+	Remove(id string) bool
+
+	// Search 返回与 query 最相似的 k 个结果，按得分降序；accept 为 nil 时不过滤
+	Search(query []float32, k int, accept func(id string) bool) []Hit
+
+	// Len 返回向量数量
+	Len() int
+}
+
+// FlatIndex 暴力检索索引，逐一比较全部向量，结果精确
+// 适合十万级以下的语料
+type FlatIndex struct {
+	metric  vector.Metric
+	ids     []string
+	vectors [][]float32
+	pos     map[string]int
+}
+
+// NewFlatIndex 创建暴力检索索引
+func NewFlatIndex(metric vector.Metric) *FlatIndex {
+	return &FlatIndex{
+		metric: metric,
+		pos:    make(map[string]int),
+	}
+}
+
+// Add 添加向量，id 已存在时替换
+func (f *FlatIndex) Add(id string, vec []float32) error {
+	if i, ok := f.pos[id]; ok {
+		f.vectors[i] = vec
+		return nil
+	}
+	f.pos[id] = len(f.ids)
+	f.ids = append(f.ids, id)
+	f.vectors = append(f.vectors, vec)
+	return nil
+}
+
+// Remove 删除向量，用最后一个元素填补空位
+func (f *FlatIndex) Remove(id string) bool {
+	i, ok := f.pos[id]
+	if !ok {
+		return false
+	}
+
+	last := len(f.ids) - 1
+	f.ids[i], f.vectors[i] = f.ids[last], f.vectors[last]
+	f.pos[f.ids[i]] = i
+	f.ids, f.vectors = f.ids[:last], f.vectors[:last]
+	delete(f.pos, id)
+	return true
+}
+
+// Search 返回与 query 最相似的 k 个结果
+func (f *FlatIndex) Search(query []float32, k int, accept func(id string) bool) []Hit {
+	var filter func(i int) bool
+	if accept != nil {
+		filter = func(i int) bool { return accept(f.ids[i]) }
+	}
+
+	results := vector.TopKFiltered(query, f.vectors, k, f.metric, filter)
+	hits := make([]Hit, len(results))
+	for i, r := range results {
+		hits[i] = Hit{ID: f.ids[r.Index], Score: r.Score}
+	}
+	return hits
+}
+
+// Len 返回向量数量
+func (f *FlatIndex) Len() int {
+	return len(f.ids)
+}
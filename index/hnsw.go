@@ -0,0 +1,577 @@
+package index
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/Kizunad/modular-embedder/vector"
+)
+
+// hnswVersion HNSW序列化格式版本
+const hnswVersion = 1
+
+// HNSWConfig HNSW索引参数
+type HNSWConfig struct {
+	// Dimension 向量维度，通常取 Embedder.GetDimension()，为 0 时取第一个插入的向量的维度
+	Dimension int
+	// Metric 相似度度量，cosine 会在插入和查询时归一化后按点积计算
+	Metric vector.Metric
+	// M 每层的最大连接数（第 0 层为 2M），默认 16，小于 2 时按 2 处理
+	M int
+	// EfConstruction 构建时的候选集大小，越大召回越高、插入越慢，默认 200
+	EfConstruction int
+	// EfSearch 查询时的候选集大小，不小于 k，默认 64
+	EfSearch int
+	// Seed 层级随机数种子，便于复现
+	Seed int64
+}
+
+// withDefaults 填充默认参数
+func (c HNSWConfig) withDefaults() HNSWConfig {
+	if c.Metric == "" {
+		c.Metric = vector.Cosine
+	}
+	if c.M <= 0 {
+		c.M = 16
+	}
+	if c.M < 2 {
+		// 层级分布按 1/ln(M) 缩放，M 为 1 时无意义
+		c.M = 2
+	}
+	if c.EfConstruction <= 0 {
+		c.EfConstruction = 200
+	}
+	if c.EfSearch <= 0 {
+		c.EfSearch = 64
+	}
+	return c
+}
+
+// hnswNode 图中的节点
+type hnswNode struct {
+	ID        string
+	Vector    []float32
+	Neighbors [][]int32 // 每层的邻居
+	Deleted   bool
+}
+
+// HNSWIndex 分层可导航小世界图（HNSW）近似最近邻索引
+// 删除采用墓碑标记：节点保留在图中用于导航，但不再出现在结果里
+type HNSWIndex struct {
+	config     HNSWConfig
+	nodes      []hnswNode
+	ids        map[string]int32
+	entry      int32
+	maxLevel   int
+	deleted    int
+	levelScale float64
+	rng        *rand.Rand
+	visited    sync.Pool
+}
+
+// NewHNSW 创建HNSW索引
+func NewHNSW(config HNSWConfig) *HNSWIndex {
+	config = config.withDefaults()
+	h := &HNSWIndex{
+		config:     config,
+		ids:        make(map[string]int32),
+		entry:      -1,
+		levelScale: 1 / math.Log(float64(config.M)),
+		rng:        rand.New(rand.NewSource(config.Seed)),
+	}
+	h.visited.New = func() interface{} { return &visitedSet{} }
+	return h
+}
+
+// Add 插入向量，id 已存在时旧节点被标记删除后重新插入
+func (h *HNSWIndex) Add(id string, vec []float32) error {
+	if len(vec) == 0 {
+		return fmt.Errorf("empty vector for %s", id)
+	}
+	if h.config.Dimension == 0 {
+		h.config.Dimension = len(vec)
+	}
+	if len(vec) != h.config.Dimension {
+		return fmt.Errorf("vector dimension %d does not match index dimension %d", len(vec), h.config.Dimension)
+	}
+	h.Remove(id)
+
+	vec = h.prepare(vec)
+	level := h.randomLevel()
+	node := int32(len(h.nodes))
+	h.nodes = append(h.nodes, hnswNode{ID: id, Vector: vec, Neighbors: make([][]int32, level+1)})
+	h.ids[id] = node
+
+	if h.entry < 0 {
+		h.entry, h.maxLevel = node, level
+		return nil
+	}
+
+	// 高于新节点层级的部分贪心下降
+	ep := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedy(vec, ep, l)
+	}
+
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(vec, ep, h.config.EfConstruction, l)
+		neighbors := h.selectNeighbors(candidates, h.maxConnections(l))
+		h.nodes[node].Neighbors[l] = neighbors
+		for _, n := range neighbors {
+			h.connect(n, node, l)
+		}
+		ep = candidates[0].node
+	}
+
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = node, level
+	}
+	return nil
+}
+
+// Remove 标记删除，返回是否存在
+func (h *HNSWIndex) Remove(id string) bool {
+	node, ok := h.ids[id]
+	if !ok {
+		return false
+	}
+	h.nodes[node].Deleted = true
+	delete(h.ids, id)
+	h.deleted++
+	return true
+}
+
+// Search 返回近似最相似的 k 个结果，按得分降序
+// 过滤或墓碑导致结果不足 k 个时会扩大候选集重试；query 维度与索引不一致时返回空结果
+func (h *HNSWIndex) Search(query []float32, k int, accept func(id string) bool) []Hit {
+	if k <= 0 || h.entry < 0 || len(h.ids) == 0 {
+		return nil
+	}
+	if len(query) != h.config.Dimension {
+		return nil
+	}
+
+	query = h.prepare(query)
+	ep := h.entry
+	for l := h.maxLevel; l > 0; l-- {
+		ep = h.greedy(query, ep, l)
+	}
+
+	ef := max(h.config.EfSearch, k)
+	for {
+		candidates := h.searchLayer(query, ep, ef, 0)
+
+		hits := make([]Hit, 0, k)
+		for _, c := range candidates {
+			node := &h.nodes[c.node]
+			if node.Deleted || (accept != nil && !accept(node.ID)) {
+				continue
+			}
+			hits = append(hits, Hit{ID: node.ID, Score: h.score(c.dist)})
+			if len(hits) == k {
+				return hits
+			}
+		}
+		if ef >= len(h.nodes) {
+			return hits
+		}
+		ef *= 2
+	}
+}
+
+// Len 返回有效（未删除）的向量数量
+func (h *HNSWIndex) Len() int {
+	return len(h.ids)
+}
+
+// Deleted 返回墓碑节点数量，过多时可重建索引
+func (h *HNSWIndex) Deleted() int {
+	return h.deleted
+}
+
+// hnswSnapshot 序列化格式
+type hnswSnapshot struct {
+	Version  int
+	Config   HNSWConfig
+	Nodes    []hnswNode
+	Entry    int32
+	MaxLevel int
+}
+
+// WriteTo 序列化索引（包括墓碑节点）
+func (h *HNSWIndex) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+	err := gob.NewEncoder(cw).Encode(&hnswSnapshot{
+		Version:  hnswVersion,
+		Config:   h.config,
+		Nodes:    h.nodes,
+		Entry:    h.entry,
+		MaxLevel: h.maxLevel,
+	})
+	return cw.n, err
+}
+
+// ReadFrom 从序列化数据恢复索引，替换当前内容
+// 快照中的节点、邻居或层级越界时返回错误，当前内容保持不变
+func (h *HNSWIndex) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+	var snap hnswSnapshot
+	if err := gob.NewDecoder(cr).Decode(&snap); err != nil {
+		return cr.n, fmt.Errorf("failed to decode hnsw index: %w", err)
+	}
+	if snap.Version != hnswVersion {
+		return cr.n, fmt.Errorf("unsupported hnsw version: %d", snap.Version)
+	}
+	if err := snap.validate(); err != nil {
+		return cr.n, fmt.Errorf("invalid hnsw index: %w", err)
+	}
+
+	restored := NewHNSW(snap.Config)
+	restored.nodes = snap.Nodes
+	restored.entry = snap.Entry
+	restored.maxLevel = snap.MaxLevel
+	for i, node := range restored.nodes {
+		if node.Deleted {
+			restored.deleted++
+			continue
+		}
+		restored.ids[node.ID] = int32(i)
+	}
+
+	h.config, h.nodes, h.ids = restored.config, restored.nodes, restored.ids
+	h.entry, h.maxLevel, h.deleted = restored.entry, restored.maxLevel, restored.deleted
+	h.levelScale, h.rng = restored.levelScale, restored.rng
+	return cr.n, nil
+}
+
+// validate 检查快照的图结构，避免加载后检索时越界；未记录维度时取第一个节点的维度（私有方法）
+func (s *hnswSnapshot) validate() error {
+	if len(s.Nodes) == 0 {
+		if s.Entry != -1 {
+			return fmt.Errorf("entry %d in empty graph", s.Entry)
+		}
+		return nil
+	}
+	if len(s.Nodes) > math.MaxInt32 {
+		return fmt.Errorf("too many nodes: %d", len(s.Nodes))
+	}
+	if s.Entry < 0 || int(s.Entry) >= len(s.Nodes) {
+		return fmt.Errorf("entry %d out of range", s.Entry)
+	}
+	if s.MaxLevel < 0 || len(s.Nodes[s.Entry].Neighbors) != s.MaxLevel+1 {
+		return fmt.Errorf("max level %d does not match entry node", s.MaxLevel)
+	}
+	if s.Config.Dimension == 0 {
+		s.Config.Dimension = len(s.Nodes[0].Vector)
+	}
+
+	live := make(map[string]bool, len(s.Nodes))
+	for i, node := range s.Nodes {
+		if len(node.Vector) != s.Config.Dimension {
+			return fmt.Errorf("node %d has dimension %d, expected %d", i, len(node.Vector), s.Config.Dimension)
+		}
+		if len(node.Neighbors) == 0 || len(node.Neighbors) > s.MaxLevel+1 {
+			return fmt.Errorf("node %d has %d levels, max level is %d", i, len(node.Neighbors), s.MaxLevel)
+		}
+		for l, neighbors := range node.Neighbors {
+			for _, n := range neighbors {
+				// 检索沿邻居在同一层继续前进，邻居必须存在于该层
+				if n < 0 || int(n) >= len(s.Nodes) || len(s.Nodes[n].Neighbors) <= l {
+					return fmt.Errorf("node %d has invalid neighbor %d at level %d", i, n, l)
+				}
+			}
+		}
+		if !node.Deleted {
+			if live[node.ID] {
+				return fmt.Errorf("duplicate id %s", node.ID)
+			}
+			live[node.ID] = true
+		}
+	}
+	return nil
+}
+
+// Save 保存索引到文件
+func (h *HNSWIndex) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := h.WriteTo(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// LoadHNSW 从文件加载索引
+func LoadHNSW(path string) (*HNSWIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	h := NewHNSW(HNSWConfig{})
+	if _, err := h.ReadFrom(file); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// candidate 搜索过程中的候选节点，dist 越小越相似
+type candidate struct {
+	node int32
+	dist float32
+}
+
+// prepare 余弦度量下复制并归一化向量（私有方法）
+func (h *HNSWIndex) prepare(vec []float32) []float32 {
+	if h.config.Metric != vector.Cosine {
+		return vec
+	}
+	norm := vector.Norm(vec)
+	normalized := make([]float32, len(vec))
+	if norm == 0 {
+		return normalized
+	}
+	for i, v := range vec {
+		normalized[i] = v / norm
+	}
+	return normalized
+}
+
+// distance 计算距离，越小越相似（私有方法）
+func (h *HNSWIndex) distance(a, b []float32) float32 {
+	if h.config.Metric == vector.Euclidean {
+		return vector.SquaredEuclidean(a, b)
+	}
+	// 余弦已归一化，等价于点积
+	return -vector.DotProduct(a, b)
+}
+
+// score 将内部距离转换为与 vector.Metric.Score 一致的得分（私有方法）
+func (h *HNSWIndex) score(dist float32) float32 {
+	if h.config.Metric == vector.Euclidean {
+		return -float32(math.Sqrt(float64(dist)))
+	}
+	return -dist
+}
+
+// randomLevel 按指数分布随机选择层级（私有方法）
+func (h *HNSWIndex) randomLevel() int {
+	return int(-math.Log(1-h.rng.Float64()) * h.levelScale)
+}
+
+// maxConnections 第 l 层的最大连接数（私有方法）
+func (h *HNSWIndex) maxConnections(l int) int {
+	if l == 0 {
+		return 2 * h.config.M
+	}
+	return h.config.M
+}
+
+// greedy 在第 l 层从 ep 出发贪心寻找最近的节点（私有方法）
+func (h *HNSWIndex) greedy(query []float32, ep int32, l int) int32 {
+	best := h.distance(query, h.nodes[ep].Vector)
+	for changed := true; changed; {
+		changed = false
+		for _, n := range h.nodes[ep].Neighbors[l] {
+			if d := h.distance(query, h.nodes[n].Vector); d < best {
+				ep, best, changed = n, d, true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer 在第 l 层做宽度为 ef 的最佳优先搜索，返回按距离升序的候选（私有方法）
+func (h *HNSWIndex) searchLayer(query []float32, ep int32, ef int, l int) []candidate {
+	visited := h.visited.Get().(*visitedSet)
+	defer h.visited.Put(visited)
+	visited.reset(len(h.nodes))
+	visited.visit(ep)
+
+	start := candidate{node: ep, dist: h.distance(query, h.nodes[ep].Vector)}
+	frontier := candidateHeap{less: func(a, b candidate) bool { return a.dist < b.dist }}
+	results := candidateHeap{less: func(a, b candidate) bool { return a.dist > b.dist }}
+	frontier.push(start)
+	results.push(start)
+
+	for len(frontier.items) > 0 {
+		current := frontier.pop()
+		if current.dist > results.items[0].dist && len(results.items) >= ef {
+			break
+		}
+
+		for _, n := range h.nodes[current.node].Neighbors[l] {
+			if !visited.visit(n) {
+				continue
+			}
+			d := h.distance(query, h.nodes[n].Vector)
+			if len(results.items) < ef || d < results.items[0].dist {
+				frontier.push(candidate{node: n, dist: d})
+				results.push(candidate{node: n, dist: d})
+				if len(results.items) > ef {
+					results.pop()
+				}
+			}
+		}
+	}
+
+	sorted := results.items
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].dist < sorted[j].dist })
+	return sorted
+}
+
+// selectNeighbors 启发式选择邻居：优先保留彼此不相近的候选以保持图的连通性（私有方法）
+// candidates 需按距离升序排列
+func (h *HNSWIndex) selectNeighbors(candidates []candidate, m int) []int32 {
+	selected := make([]int32, 0, m)
+	var pruned []int32
+	for _, c := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		keep := true
+		for _, s := range selected {
+			if h.distance(h.nodes[c.node].Vector, h.nodes[s].Vector) < c.dist {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			selected = append(selected, c.node)
+		} else {
+			pruned = append(pruned, c.node)
+		}
+	}
+
+	// 启发式选出的邻居不足时用被裁剪的候选补齐
+	for _, n := range pruned {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, n)
+	}
+	return selected
+}
+
+// connect 为节点 from 在第 l 层添加指向 to 的连接，超出上限时重新选择（私有方法）
+func (h *HNSWIndex) connect(from, to int32, l int) {
+	neighbors := append(h.nodes[from].Neighbors[l], to)
+	limit := h.maxConnections(l)
+	if len(neighbors) <= limit {
+		h.nodes[from].Neighbors[l] = neighbors
+		return
+	}
+
+	base := h.nodes[from].Vector
+	candidates := make([]candidate, len(neighbors))
+	for i, n := range neighbors {
+		candidates[i] = candidate{node: n, dist: h.distance(base, h.nodes[n].Vector)}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].dist < candidates[j].dist })
+	h.nodes[from].Neighbors[l] = h.selectNeighbors(candidates, limit)
+}
+
+// candidateHeap 按 less 排序的二叉堆
+type candidateHeap struct {
+	items []candidate
+	less  func(a, b candidate) bool
+}
+
+func (c *candidateHeap) push(item candidate) {
+	c.items = append(c.items, item)
+	for i := len(c.items) - 1; i > 0; {
+		parent := (i - 1) / 2
+		if !c.less(c.items[i], c.items[parent]) {
+			break
+		}
+		c.items[i], c.items[parent] = c.items[parent], c.items[i]
+		i = parent
+	}
+}
+
+func (c *candidateHeap) pop() candidate {
+	top := c.items[0]
+	last := len(c.items) - 1
+	c.items[0] = c.items[last]
+	c.items = c.items[:last]
+
+	for i := 0; ; {
+		best := i
+		left, right := 2*i+1, 2*i+2
+		if left < last && c.less(c.items[left], c.items[best]) {
+			best = left
+		}
+		if right < last && c.less(c.items[right], c.items[best]) {
+			best = right
+		}
+		if best == i {
+			break
+		}
+		c.items[i], c.items[best] = c.items[best], c.items[i]
+		i = best
+	}
+	return top
+}
+
+// visitedSet 使用代数标记的访问集合，复用时无需清零
+type visitedSet struct {
+	marks []uint32
+	gen   uint32
+}
+
+func (v *visitedSet) reset(n int) {
+	if len(v.marks) < n {
+		v.marks = make([]uint32, n)
+		v.gen = 0
+	}
+	v.gen++
+	if v.gen == 0 {
+		for i := range v.marks {
+			v.marks[i] = 0
+		}
+		v.gen = 1
+	}
+}
+
+// visit 标记节点，首次访问时返回 true
+func (v *visitedSet) visit(n int32) bool {
+	if v.marks[n] == v.gen {
+		return false
+	}
+	v.marks[n] = v.gen
+	return true
+}
+
+// countingWriter 统计写入字节数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// countingReader 统计读取字节数
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
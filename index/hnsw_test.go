@@ -0,0 +1,208 @@
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/Kizunad/modular-embedder/vector"
+)
+
+func randomVectors(n, dim int, seed int64) [][]float32 {
+	rng := rand.New(rand.NewSource(seed))
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vectors[i] = make([]float32, dim)
+		for j := range vectors[i] {
+			vectors[i][j] = rng.Float32()*2 - 1
+		}
+	}
+	return vectors
+}
+
+func TestHNSWRecall(t *testing.T) {
+	const n, dim, k = 2000, 32, 10
+	vectors := randomVectors(n, dim, 1)
+	queries := randomVectors(50, dim, 2)
+
+	for _, metric := range []vector.Metric{vector.Cosine, vector.Dot, vector.Euclidean} {
+		hnsw := NewHNSW(HNSWConfig{Dimension: dim, Metric: metric, Seed: 1})
+		flat := NewFlatIndex(metric)
+		for i, v := range vectors {
+			id := fmt.Sprint(i)
+			if err := hnsw.Add(id, v); err != nil {
+				t.Fatalf("Add failed: %v", err)
+			}
+			flat.Add(id, v)
+		}
+
+		found, total := 0, 0
+		for _, q := range queries {
+			exact := make(map[string]bool)
+			for _, hit := range flat.Search(q, k, nil) {
+				exact[hit.ID] = true
+			}
+			for _, hit := range hnsw.Search(q, k, nil) {
+				if exact[hit.ID] {
+					found++
+				}
+			}
+			total += k
+		}
+
+		if recall := float64(found) / float64(total); recall < 0.9 {
+			t.Errorf("%s: recall %.2f below 0.9", metric, recall)
+		}
+	}
+}
+
+func TestHNSWDeleteAndFilter(t *testing.T) {
+	vectors := randomVectors(300, 8, 3)
+	hnsw := NewHNSW(HNSWConfig{Dimension: 8, Seed: 1})
+	for i, v := range vectors {
+		hnsw.Add(fmt.Sprint(i), v)
+	}
+
+	if err := hnsw.Add("bad", make([]float32, 4)); err == nil {
+		t.Error("Expected dimension mismatch error")
+	}
+
+	hits := hnsw.Search(vectors[42], 1, nil)
+	if len(hits) != 1 || hits[0].ID != "42" {
+		t.Fatalf("Expected exact match 42, got %v", hits)
+	}
+
+	if !hnsw.Remove("42") || hnsw.Len() != 299 || hnsw.Deleted() != 1 {
+		t.Fatal("Expected 42 to be tombstoned")
+	}
+	for _, hit := range hnsw.Search(vectors[42], 5, nil) {
+		if hit.ID == "42" {
+			t.Error("Tombstoned vector returned by Search")
+		}
+	}
+
+	onlyEven := func(id string) bool {
+		var n int
+		fmt.Sscan(id, &n)
+		return n%2 == 0
+	}
+	hits = hnsw.Search(vectors[7], 20, onlyEven)
+	if len(hits) != 20 {
+		t.Errorf("Expected 20 filtered results, got %d", len(hits))
+	}
+	for _, hit := range hits {
+		if !onlyEven(hit.ID) {
+			t.Errorf("Filter not applied: %s", hit.ID)
+		}
+	}
+}
+
+func TestHNSWSmallMAndDimensionMismatch(t *testing.T) {
+	// M 为 1 时按 2 处理，不会得到无穷大的层级
+	hnsw := NewHNSW(HNSWConfig{M: 1, Seed: 1})
+	for i, v := range randomVectors(50, 4, 1) {
+		if err := hnsw.Add(fmt.Sprint(i), v); err != nil {
+			t.Fatalf("Add failed: %v", err)
+		}
+	}
+	if hits := hnsw.Search(randomVectors(1, 4, 2)[0], 5, nil); len(hits) != 5 {
+		t.Fatalf("Expected 5 hits, got %d", len(hits))
+	}
+
+	// 未配置维度时按第一个向量确定
+	if hits := hnsw.Search([]float32{1, 0}, 5, nil); hits != nil {
+		t.Errorf("Expected no hits for mismatched query dimension, got %v", hits)
+	}
+	if err := hnsw.Add("short", []float32{1, 0}); err == nil {
+		t.Error("Expected dimension mismatch error after the dimension is pinned")
+	}
+}
+
+func TestHNSWRejectsCorruptSnapshot(t *testing.T) {
+	hnsw := NewHNSW(HNSWConfig{Dimension: 4, M: 2, Seed: 1})
+	for i, v := range randomVectors(50, 4, 5) {
+		hnsw.Add(fmt.Sprint(i), v)
+	}
+
+	cases := map[string]func(s *hnswSnapshot){
+		"entry out of range":    func(s *hnswSnapshot) { s.Entry = int32(len(s.Nodes)) },
+		"max level too high":    func(s *hnswSnapshot) { s.MaxLevel++ },
+		"neighbor out of range": func(s *hnswSnapshot) { s.Nodes[1].Neighbors[0] = append(s.Nodes[1].Neighbors[0], -1) },
+		"node above max level":  func(s *hnswSnapshot) { s.Nodes[1].Neighbors = make([][]int32, s.MaxLevel+2) },
+		"vector dimension":      func(s *hnswSnapshot) { s.Nodes[2].Vector = s.Nodes[2].Vector[:2] },
+	}
+	for name, corrupt := range cases {
+		var buf bytes.Buffer
+		hnsw.WriteTo(&buf)
+		var snap hnswSnapshot
+		if err := gob.NewDecoder(&buf).Decode(&snap); err != nil {
+			t.Fatalf("decode failed: %v", err)
+		}
+		corrupt(&snap)
+		buf.Reset()
+		gob.NewEncoder(&buf).Encode(&snap)
+
+		restored := NewHNSW(HNSWConfig{})
+		if _, err := restored.ReadFrom(&buf); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+		if restored.Len() != 0 {
+			t.Errorf("%s: expected index to stay empty, got %d", name, restored.Len())
+		}
+	}
+}
+
+func TestHNSWSerialization(t *testing.T) {
+	vectors := randomVectors(200, 16, 4)
+	hnsw := NewHNSW(HNSWConfig{Dimension: 16, Metric: vector.Euclidean, M: 8, Seed: 1})
+	for i, v := range vectors {
+		hnsw.Add(fmt.Sprint(i), v)
+	}
+	hnsw.Remove("3")
+
+	path := filepath.Join(t.TempDir(), "index.hnsw")
+	if err := hnsw.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	restored, err := LoadHNSW(path)
+	if err != nil {
+		t.Fatalf("LoadHNSW failed: %v", err)
+	}
+	if restored.Len() != hnsw.Len() || restored.Deleted() != 1 {
+		t.Errorf("Expected %d vectors and 1 tombstone, got %d and %d", hnsw.Len(), restored.Len(), restored.Deleted())
+	}
+
+	want := hnsw.Search(vectors[10], 5, nil)
+	got := restored.Search(vectors[10], 5, nil)
+	for i := range want {
+		if want[i] != got[i] {
+			t.Fatalf("Restored search differs: %v vs %v", got, want)
+		}
+	}
+
+	// 索引快照直接保存 HNSW 图，加载后不再重建
+	idx := New(&bagOfWordsEmbedder{model: "bow"}, NewHNSW(HNSWConfig{Dimension: 256}))
+	idx.Add(context.Background(), "a", "hello world", nil)
+	var buf bytes.Buffer
+	idx.structure.(PersistentIndex).WriteTo(&buf)
+	if buf.Len() == 0 {
+		t.Error("Expected HNSW structure to serialize")
+	}
+
+	snapshot := filepath.Join(t.TempDir(), "index.snapshot")
+	if err := idx.Save(snapshot); err != nil {
+		t.Fatalf("Index Save failed: %v", err)
+	}
+	loaded := New(&bagOfWordsEmbedder{model: "bow"}, NewHNSW(HNSWConfig{}))
+	if err := loaded.Load(snapshot); err != nil {
+		t.Fatalf("Index Load failed: %v", err)
+	}
+	results, _ := loaded.Search(context.Background(), "hello", 1, nil)
+	if len(results) != 1 || results[0].Document.ID != "a" {
+		t.Errorf("Unexpected results after loading HNSW snapshot: %+v", results)
+	}
+}
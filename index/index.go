@@ -0,0 +1,300 @@
+// Package index 提供进程内的文本向量索引：嵌入、检索、删除以及快照持久化
+// 适合无需外部向量数据库的小型语料，索引结构可通过 VectorIndex 替换
+package index
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	embedder "github.com/Kizunad/modular-embedder"
+	"github.com/Kizunad/modular-embedder/vector"
+)
+
+// snapshotVersion 快照格式版本
+const snapshotVersion = 1
+
+// addBatchSize AddBatch 每次嵌入的文档数
+const addBatchSize = 64
+
+// ErrModelMismatch 快照由其他模型生成，向量不可混用
+var ErrModelMismatch = errors.New("snapshot model mismatch")
+
+// ErrDimensionMismatch 快照中的向量维度与当前 Embedder 不一致
+var ErrDimensionMismatch = errors.New("snapshot dimension mismatch")
+
+// Document 索引中的文档
+type Document struct {
+	ID       string
+	Text     string
+	Metadata map[string]string
+	Vector   []float32
+}
+
+// SearchResult 检索结果
+type SearchResult struct {
+	Document Document
+	Score    float32
+}
+
+// Filter 检索过滤条件，返回 false 的文档会被跳过
+type Filter func(doc Document) bool
+
+// MatchMetadata 创建按元数据精确匹配的过滤条件
+func MatchMetadata(key, value string) Filter {
+	return func(doc Document) bool {
+		return doc.Metadata[key] == value
+	}
+}
+
+// PersistentIndex 可序列化的索引结构
+// 快照会直接保存其内部状态，加载时无需重建（如 HNSW 图）
+type PersistentIndex interface {
+	VectorIndex
+	io.WriterTo
+	io.ReaderFrom
+}
+
+// Index 基于 Embedder 的文本向量索引，并发安全
+type Index struct {
+	embedder  embedder.Embedder
+	structure VectorIndex
+	docs      map[string]Document
+	mu        sync.RWMutex
+}
+
+// New 创建索引，structure 为 nil 时使用余弦相似度的 FlatIndex
+func New(e embedder.Embedder, structure VectorIndex) *Index {
+	if structure == nil {
+		structure = NewFlatIndex(vector.Cosine)
+	}
+	return &Index{
+		embedder:  e,
+		structure: structure,
+		docs:      make(map[string]Document),
+	}
+}
+
+// Add 嵌入文本并加入索引，id 已存在时替换
+func (idx *Index) Add(ctx context.Context, id, text string, metadata map[string]string) error {
+	return idx.AddBatch(ctx, []Document{{ID: id, Text: text, Metadata: metadata}})
+}
+
+// AddBatch 批量嵌入并加入索引，已带有 Vector 的文档不再嵌入
+func (idx *Index) AddBatch(ctx context.Context, docs []Document) error {
+	docs = append([]Document(nil), docs...)
+
+	var pending []int
+	for i := range docs {
+		if docs[i].Vector == nil {
+			pending = append(pending, i)
+		}
+	}
+
+	for start := 0; start < len(pending); start += addBatchSize {
+		end := start + addBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		texts := make([]string, end-start)
+		for j, i := range pending[start:end] {
+			texts[j] = docs[i].Text
+		}
+		embeddings, err := embedder.EmbedDocuments(ctx, idx.embedder, texts)
+		if err != nil {
+			return fmt.Errorf("failed to embed documents: %w", err)
+		}
+		if len(embeddings) != len(texts) {
+			return fmt.Errorf("%w: expected %d embeddings, got %d", embedder.ErrInvalidResponse, len(texts), len(embeddings))
+		}
+		for j, i := range pending[start:end] {
+			docs[i].Vector = embeddings[j]
+		}
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	for _, doc := range docs {
+		if err := idx.structure.Add(doc.ID, doc.Vector); err != nil {
+			return fmt.Errorf("failed to index document %s: %w", doc.ID, err)
+		}
+		idx.docs[doc.ID] = doc
+	}
+	return nil
+}
+
+// Search 以查询模式嵌入 query 并返回最相似的 k 个文档，filter 可为 nil
+func (idx *Index) Search(ctx context.Context, query string, k int, filter Filter) ([]SearchResult, error) {
+	queryVector, err := embedder.EmbedQuery(ctx, idx.embedder, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	return idx.SearchVector(queryVector, k, filter), nil
+}
+
+// SearchVector 使用已有的查询向量检索
+func (idx *Index) SearchVector(query []float32, k int, filter Filter) []SearchResult {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var accept func(id string) bool
+	if filter != nil {
+		accept = func(id string) bool { return filter(idx.docs[id]) }
+	}
+
+	hits := idx.structure.Search(query, k, accept)
+	results := make([]SearchResult, len(hits))
+	for i, hit := range hits {
+		results[i] = SearchResult{Document: idx.docs[hit.ID], Score: hit.Score}
+	}
+	return results
+}
+
+// Get 按 id 获取文档
+func (idx *Index) Get(id string) (Document, bool) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	doc, ok := idx.docs[id]
+	return doc, ok
+}
+
+// Delete 删除文档，返回是否存在
+func (idx *Index) Delete(id string) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if _, ok := idx.docs[id]; !ok {
+		return false
+	}
+	idx.structure.Remove(id)
+	delete(idx.docs, id)
+	return true
+}
+
+// Len 返回文档数量
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return len(idx.docs)
+}
+
+// snapshot 快照文件内容
+type snapshot struct {
+	Version   int
+	Model     string
+	Documents []Document
+	// Structure PersistentIndex 的序列化数据，为空时加载后重建索引结构
+	Structure []byte
+}
+
+// Save 将文档和向量保存到本地文件，先写临时文件再重命名，避免留下不完整的快照
+func (idx *Index) Save(path string) error {
+	idx.mu.RLock()
+	snap := snapshot{
+		Version:   snapshotVersion,
+		Model:     idx.embedder.GetModel(),
+		Documents: make([]Document, 0, len(idx.docs)),
+	}
+	for _, doc := range idx.docs {
+		snap.Documents = append(snap.Documents, doc)
+	}
+	if persistent, ok := idx.structure.(PersistentIndex); ok {
+		var buf bytes.Buffer
+		if _, err := persistent.WriteTo(&buf); err != nil {
+			idx.mu.RUnlock()
+			return fmt.Errorf("failed to encode index structure: %w", err)
+		}
+		snap.Structure = buf.Bytes()
+	}
+	idx.mu.RUnlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(&snap); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Load 从快照文件恢复，替换索引中的全部文档
+// 快照模型与当前 Embedder 不一致时返回 ErrModelMismatch，向量维度不一致时返回 ErrDimensionMismatch；
+// 加载失败时索引保持原样
+func (idx *Index) Load(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var snap snapshot
+	if err := gob.NewDecoder(file).Decode(&snap); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version: %d", snap.Version)
+	}
+	if model := idx.embedder.GetModel(); snap.Model != model {
+		return fmt.Errorf("%w: snapshot %s, embedder %s", ErrModelMismatch, snap.Model, model)
+	}
+
+	docs := make(map[string]Document, len(snap.Documents))
+	dimension := idx.embedder.GetDimension()
+	for _, doc := range snap.Documents {
+		if dimension > 0 && len(doc.Vector) != dimension {
+			return fmt.Errorf("%w: document %s has dimension %d, embedder %d", ErrDimensionMismatch, doc.ID, len(doc.Vector), dimension)
+		}
+		docs[doc.ID] = doc
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	// PersistentIndex 的 ReadFrom 只在成功时替换内容
+	if persistent, ok := idx.structure.(PersistentIndex); ok && snap.Structure != nil {
+		if _, err := persistent.ReadFrom(bytes.NewReader(snap.Structure)); err != nil {
+			return fmt.Errorf("failed to decode index structure: %w", err)
+		}
+		idx.docs = docs
+		return nil
+	}
+
+	// 索引结构不可序列化时按文档重建，失败时恢复原有文档
+	if err := idx.replace(idx.docs, docs); err != nil {
+		idx.replace(docs, idx.docs)
+		return err
+	}
+	idx.docs = docs
+	return nil
+}
+
+// replace 从索引结构中移除 old 的全部文档并加入 docs，调用方需持有写锁（私有方法）
+func (idx *Index) replace(old, docs map[string]Document) error {
+	for id := range old {
+		idx.structure.Remove(id)
+	}
+	for _, doc := range docs {
+		if err := idx.structure.Add(doc.ID, doc.Vector); err != nil {
+			return fmt.Errorf("failed to index document %s: %w", doc.ID, err)
+		}
+	}
+	return nil
+}
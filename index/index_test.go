@@ -0,0 +1,187 @@
+package index
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	embedder "github.com/Kizunad/modular-embedder"
+)
+
+// bagOfWordsEmbedder 将单词哈希到固定维度的测试嵌入服务
+type bagOfWordsEmbedder struct {
+	model string
+	calls int
+}
+
+func (b *bagOfWordsEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	b.calls++
+	result := make([][]float32, len(texts))
+	for i, text := range texts {
+		result[i], _ = b.EmbedSingle(ctx, text)
+	}
+	return result, nil
+}
+
+func (b *bagOfWordsEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	vec := make([]float32, 256)
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		vec[h.Sum32()%256]++
+	}
+	return vec, nil
+}
+
+func (b *bagOfWordsEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	return b.Embed(ctx, texts)
+}
+
+func (b *bagOfWordsEmbedder) GetDimension() int                { return 256 }
+func (b *bagOfWordsEmbedder) GetModel() string                 { return b.model }
+func (b *bagOfWordsEmbedder) Health(ctx context.Context) error { return nil }
+
+func newTestIndex(t *testing.T) *Index {
+	idx := New(&bagOfWordsEmbedder{model: "bow"}, nil)
+	err := idx.AddBatch(context.Background(), []Document{
+		{ID: "go", Text: "go is a programming language", Metadata: map[string]string{"lang": "en"}},
+		{ID: "rust", Text: "rust is a systems programming language", Metadata: map[string]string{"lang": "en"}},
+		{ID: "cat", Text: "the cat sat on the mat", Metadata: map[string]string{"lang": "en"}},
+		{ID: "zh", Text: "go 语言 programming", Metadata: map[string]string{"lang": "zh"}},
+	})
+	if err != nil {
+		t.Fatalf("AddBatch failed: %v", err)
+	}
+	return idx
+}
+
+func TestIndexSearch(t *testing.T) {
+	idx := newTestIndex(t)
+	ctx := context.Background()
+
+	results, err := idx.Search(ctx, "the cat sat", 1, nil)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Document.ID != "cat" {
+		t.Errorf("Expected cat document, got %+v", results)
+	}
+
+	results, _ = idx.Search(ctx, "go programming", 4, MatchMetadata("lang", "zh"))
+	if len(results) != 1 || results[0].Document.ID != "zh" {
+		t.Errorf("Expected only the zh document, got %+v", results)
+	}
+
+	if !idx.Delete("cat") || idx.Delete("cat") {
+		t.Error("Expected Delete to report existence once")
+	}
+	results, _ = idx.Search(ctx, "the cat sat", 4, nil)
+	for _, r := range results {
+		if r.Document.ID == "cat" {
+			t.Error("Deleted document returned by Search")
+		}
+	}
+	if idx.Len() != 3 {
+		t.Errorf("Expected 3 documents, got %d", idx.Len())
+	}
+}
+
+func TestIndexSaveLoad(t *testing.T) {
+	idx := newTestIndex(t)
+	path := filepath.Join(t.TempDir(), "index.snapshot")
+	if err := idx.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	inner := &bagOfWordsEmbedder{model: "bow"}
+	restored := New(inner, nil)
+	if err := restored.Load(path); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if restored.Len() != 4 || inner.calls != 0 {
+		t.Errorf("Expected 4 documents restored without embedding, got %d after %d calls", restored.Len(), inner.calls)
+	}
+	doc, ok := restored.Get("rust")
+	if !ok || doc.Metadata["lang"] != "en" || len(doc.Vector) != 256 {
+		t.Errorf("Unexpected restored document: %+v", doc)
+	}
+
+	other := New(&bagOfWordsEmbedder{model: "other"}, nil)
+	if err := other.Load(path); !errors.Is(err, ErrModelMismatch) {
+		t.Errorf("Expected ErrModelMismatch, got %v", err)
+	}
+}
+
+// resizedEmbedder 模型名相同但维度不同的测试嵌入服务
+type resizedEmbedder struct {
+	bagOfWordsEmbedder
+	dimension int
+}
+
+func (r *resizedEmbedder) GetDimension() int { return r.dimension }
+
+// shortEmbedder 返回的向量少于输入数量的测试嵌入服务
+type shortEmbedder struct {
+	bagOfWordsEmbedder
+}
+
+func (s *shortEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings, err := s.bagOfWordsEmbedder.Embed(ctx, texts)
+	return embeddings[:len(embeddings)-1], err
+}
+
+func TestIndexAddBatchRejectsShortResponse(t *testing.T) {
+	idx := New(&shortEmbedder{bagOfWordsEmbedder{model: "bow"}}, nil)
+	err := idx.AddBatch(context.Background(), []Document{{ID: "a", Text: "a"}, {ID: "b", Text: "b"}})
+	if !errors.Is(err, embedder.ErrInvalidResponse) {
+		t.Errorf("Expected ErrInvalidResponse, got %v", err)
+	}
+	if idx.Len() != 0 {
+		t.Errorf("Expected no documents after a failed batch, got %d", idx.Len())
+	}
+}
+
+func TestIndexLoadKeepsDocumentsOnFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.snapshot")
+	if err := newTestIndex(t).Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	resized := New(&resizedEmbedder{bagOfWordsEmbedder{model: "bow"}, 128}, nil)
+	if err := resized.Load(path); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("Expected ErrDimensionMismatch, got %v", err)
+	}
+
+	// 索引结构损坏时保留原有文档
+	corrupt := filepath.Join(t.TempDir(), "corrupt.snapshot")
+	file, err := os.Create(corrupt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gob.NewEncoder(file).Encode(&snapshot{
+		Version:   snapshotVersion,
+		Model:     "bow",
+		Documents: []Document{{ID: "new", Vector: make([]float32, 256)}},
+		Structure: []byte("garbage"),
+	})
+	file.Close()
+
+	idx := New(&bagOfWordsEmbedder{model: "bow"}, NewHNSW(HNSWConfig{Dimension: 256}))
+	if err := idx.Add(context.Background(), "go", "go is a programming language", nil); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	if err := idx.Load(corrupt); err == nil {
+		t.Error("Expected error for a corrupt index structure")
+	}
+	if _, ok := idx.Get("go"); !ok || idx.Len() != 1 {
+		t.Errorf("Expected existing documents to be kept, got %d documents", idx.Len())
+	}
+	if hits := idx.SearchVector(idx.docs["go"].Vector, 1, nil); len(hits) != 1 || hits[0].Document.ID != "go" {
+		t.Errorf("Expected the existing structure to be kept, got %v", hits)
+	}
+}
@@ -0,0 +1,152 @@
+package embedder
+
+import (
+	"context"
+	"strings"
+)
+
+// 查询/文档前缀的选项键，值可以是前缀，或包含 {text} 占位符的模板
+const (
+	// OptionQueryPrefix 查询文本的前缀模板
+	OptionQueryPrefix = "query_prefix"
+	// OptionDocumentPrefix 文档文本的前缀模板
+	OptionDocumentPrefix = "document_prefix"
+)
+
+// RetrievalEmbedder 区分查询与文档的嵌入服务扩展接口
+// nomic-embed-text、e5、bge 等模型对查询和文档需要不同的前缀
+type RetrievalEmbedder interface {
+	Embedder
+
+	// EmbedQuery 以查询模式嵌入文本
+	EmbedQuery(ctx context.Context, query string) ([]float32, error)
+
+	// EmbedDocuments 以文档模式嵌入多个文本
+	EmbedDocuments(ctx context.Context, documents []string) ([][]float32, error)
+}
+
+// PromptTemplate 查询与文档的前缀模板
+type PromptTemplate struct {
+	Query    string `yaml:"query"`
+	Document string `yaml:"document"`
+}
+
+// IsZero 是否未设置任何前缀
+func (t PromptTemplate) IsZero() bool {
+	return t.Query == "" && t.Document == ""
+}
+
+// applyTemplate 按模板生成输入文本，模板不含 {text} 时作为前缀
+func applyTemplate(template, text string) string {
+	if template == "" {
+		return text
+	}
+	if strings.Contains(template, "{text}") {
+		return strings.ReplaceAll(template, "{text}", text)
+	}
+	return template + text
+}
+
+// RegisterPromptTemplate 注册或覆盖模型的前缀模板，保留模型的其他信息
+// model 按前缀匹配，例如 "e5-large" 匹配 "e5-large-v2"
+func RegisterPromptTemplate(model string, template PromptTemplate) {
+	modelRegistryMu.Lock()
+	defer modelRegistryMu.Unlock()
+
+	key := modelNameWithTag(model)
+	info, ok := modelRegistry[key]
+	if !ok {
+		info = ModelMetadata{Name: model}
+	}
+	info.Prompt = template
+	modelRegistry[key] = info
+}
+
+// LookupPromptTemplate 查找模型的前缀模板
+func LookupPromptTemplate(model string) (PromptTemplate, bool) {
+	info, ok := ModelInfo(model)
+	if !ok || info.Prompt.IsZero() {
+		return PromptTemplate{}, false
+	}
+	return info.Prompt, true
+}
+
+// resolvePromptTemplate 合并内置模板与 Config.Options 中的配置，选项优先
+func resolvePromptTemplate(config Config) PromptTemplate {
+	template, _ := LookupPromptTemplate(config.Model)
+	template.Query = optionString(config.Options, OptionQueryPrefix, template.Query)
+	template.Document = optionString(config.Options, OptionDocumentPrefix, template.Document)
+	return template
+}
+
+// InstructionEmbedder 为查询与文档添加前缀的装饰器
+// Embed、EmbedSingle、BatchEmbed 保持原样，不添加前缀
+type InstructionEmbedder struct {
+	inner    Embedder
+	template PromptTemplate
+}
+
+// NewInstructionEmbedder 使用前缀模板包装嵌入服务
+func NewInstructionEmbedder(inner Embedder, template PromptTemplate) *InstructionEmbedder {
+	return &InstructionEmbedder{inner: inner, template: template}
+}
+
+// EmbedQuery 以查询模式嵌入文本
+func (e *InstructionEmbedder) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	return e.inner.EmbedSingle(ctx, applyTemplate(e.template.Query, query))
+}
+
+// EmbedDocuments 以文档模式嵌入多个文本
+func (e *InstructionEmbedder) EmbedDocuments(ctx context.Context, documents []string) ([][]float32, error) {
+	inputs := make([]string, len(documents))
+	for i, document := range documents {
+		inputs[i] = applyTemplate(e.template.Document, document)
+	}
+	return e.inner.Embed(ctx, inputs)
+}
+
+// Embed 批量嵌入多个文本
+func (e *InstructionEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return e.inner.Embed(ctx, texts)
+}
+
+// EmbedSingle 嵌入单个文本
+func (e *InstructionEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	return e.inner.EmbedSingle(ctx, text)
+}
+
+// BatchEmbed 分批处理大量文本
+func (e *InstructionEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	return e.inner.BatchEmbed(ctx, texts, batchSize)
+}
+
+// GetDimension 获取嵌入维度
+func (e *InstructionEmbedder) GetDimension() int {
+	return e.inner.GetDimension()
+}
+
+// GetModel 获取模型名称
+func (e *InstructionEmbedder) GetModel() string {
+	return e.inner.GetModel()
+}
+
+// Health 健康检查
+func (e *InstructionEmbedder) Health(ctx context.Context) error {
+	return e.inner.Health(ctx)
+}
+
+// EmbedQuery 以查询模式嵌入文本，嵌入服务不支持 RetrievalEmbedder 时直接嵌入
+func EmbedQuery(ctx context.Context, e Embedder, query string) ([]float32, error) {
+	if r, ok := e.(RetrievalEmbedder); ok {
+		return r.EmbedQuery(ctx, query)
+	}
+	return e.EmbedSingle(ctx, query)
+}
+
+// EmbedDocuments 以文档模式嵌入多个文本，嵌入服务不支持 RetrievalEmbedder 时直接嵌入
+func EmbedDocuments(ctx context.Context, e Embedder, documents []string) ([][]float32, error) {
+	if r, ok := e.(RetrievalEmbedder); ok {
+		return r.EmbedDocuments(ctx, documents)
+	}
+	return e.Embed(ctx, documents)
+}
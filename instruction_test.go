@@ -0,0 +1,106 @@
+package embedder
+
+import (
+	"context"
+	"testing"
+)
+
+func TestLookupPromptTemplate(t *testing.T) {
+	cases := []struct {
+		model string
+		query string
+		found bool
+	}{
+		{"nomic-embed-text:latest", "search_query: ", true},
+		{"intfloat/e5-large-v2", "query: ", true},
+		{"intfloat/multilingual-e5-base", "query: ", true},
+		{"BAAI/bge-base-en-v1.5", bgeQueryInstruction, true},
+		{"bge-m3", "", false},
+		{"qwen2.5:7b", "", false},
+	}
+	for _, c := range cases {
+		template, found := LookupPromptTemplate(c.model)
+		if found != c.found || template.Query != c.query {
+			t.Errorf("LookupPromptTemplate(%q) = %+v, %v", c.model, template, found)
+		}
+	}
+}
+
+func TestApplyTemplate(t *testing.T) {
+	if got := applyTemplate("query: ", "hi"); got != "query: hi" {
+		t.Errorf("Unexpected prefix result: %q", got)
+	}
+	if got := applyTemplate("<q>{text}</q>", "hi"); got != "<q>hi</q>" {
+		t.Errorf("Unexpected template result: %q", got)
+	}
+}
+
+func TestFactoryAppliesPromptTemplate(t *testing.T) {
+	inner := &countingEmbedder{}
+	factory := NewFactory()
+	factory.RegisterProvider("mock", func(config Config) (Embedder, error) {
+		return inner, nil
+	})
+
+	embedder, err := factory.CreateWithConfig(Config{
+		Provider: "mock",
+		Model:    "nomic-embed-text",
+		Options:  map[string]interface{}{OptionDocumentPrefix: "doc: "},
+	})
+	if err != nil {
+		t.Fatalf("CreateWithConfig failed: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := EmbedQuery(ctx, embedder, "q"); err != nil {
+		t.Fatalf("EmbedQuery failed: %v", err)
+	}
+	if _, err := EmbedDocuments(ctx, embedder, []string{"d"}); err != nil {
+		t.Fatalf("EmbedDocuments failed: %v", err)
+	}
+	if _, err := embedder.Embed(ctx, []string{"raw"}); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+
+	want := []string{"search_query: q", "doc: d", "raw"}
+	for i, text := range want {
+		if i >= len(inner.received) || inner.received[i] != text {
+			t.Fatalf("Expected inputs %q, got %q", want, inner.received)
+		}
+	}
+
+	// 没有模板的模型保持原样
+	plain, _ := factory.CreateWithConfig(Config{Provider: "mock", Model: "qwen2.5:7b"})
+	if _, ok := plain.(RetrievalEmbedder); ok {
+		t.Error("Expected no instruction wrapper for models without prompt templates")
+	}
+}
+
+func TestDecoratorsPreserveRetrievalMode(t *testing.T) {
+	inner := &countingEmbedder{}
+	instruction := NewInstructionEmbedder(inner, PromptTemplate{Query: "q: ", Document: "d: "})
+	e := NewCacheEmbedder(
+		NewPostProcessEmbedder(
+			NewOverflowEmbedder(NewRetryEmbedder(instruction, DefaultRetryPolicy), OverflowPolicy{MaxChars: 100}),
+			Normalize()),
+		NewMemoryCache(0), "mock")
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := EmbedQuery(ctx, e, "text"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := EmbedDocuments(ctx, e, []string{"text"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 查询与文档分开缓存，第二轮全部命中
+	want := []string{"q: text", "d: text"}
+	if len(inner.received) != len(want) || inner.received[0] != want[0] || inner.received[1] != want[1] {
+		t.Fatalf("Expected inputs %q, got %q", want, inner.received)
+	}
+	if hits, misses := e.Stats(); hits != 2 || misses != 2 {
+		t.Errorf("Expected 2 hits and 2 misses, got %d/%d", hits, misses)
+	}
+}
@@ -2,6 +2,7 @@ package embedder
 
 import (
 	"context"
+	"log/slog"
 	"time"
 )
 
@@ -43,6 +44,10 @@ type EmbedderFactory interface {
 // ProviderFunc provider创建函数类型
 type ProviderFunc func(config Config) (Embedder, error)
 
+// ProviderFuncCtx 支持context的provider创建函数类型
+// 构造时需要网络访问的provider可以借此响应取消和超时
+type ProviderFuncCtx func(ctx context.Context, config Config) (Embedder, error)
+
 // Config 嵌入服务配置
 type Config struct {
 	Provider string                 `yaml:"provider"`
@@ -50,6 +55,19 @@ type Config struct {
 	Model    string                 `yaml:"model"`
 	Timeout  time.Duration          `yaml:"timeout"`
 	Options  map[string]interface{} `yaml:"options"`
+	Retry    RetryPolicy            `yaml:"retry"`
+	Overflow OverflowPolicy         `yaml:"overflow"`
+	// BaseURLs 多个服务地址，设置后为每个地址创建一个后端并按 Balance 负载均衡，忽略 BaseURL
+	BaseURLs []string      `yaml:"base_urls"`
+	Balance  BalancePolicy `yaml:"balance"`
+	// Limit 客户端限流策略，设置后对每个后端单独限流
+	Limit LimitPolicy `yaml:"limit"`
+	// Circuit 熔断策略，设置了熔断条件时包装每个后端
+	Circuit CircuitPolicy `yaml:"circuit"`
+	// Logger 日志输出，为 nil 时使用包级默认日志（默认静默）
+	Logger *slog.Logger `yaml:"-"`
+	// Tracer 链路追踪钩子，为 nil 时不记录
+	Tracer Tracer `yaml:"-"`
 }
 
 // DefaultConfig 默认配置
@@ -59,4 +77,5 @@ var DefaultConfig = Config{
 	Model:    "qwen2.5:7b",
 	Timeout:  30 * time.Second,
 	Options:  make(map[string]interface{}),
+	Retry:    DefaultRetryPolicy,
 }
\ No newline at end of file
@@ -0,0 +1,260 @@
+package embedder
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// LimitPolicy 客户端限流策略，零值字段表示不限制该项
+// 各速率限制为令牌桶，容量为一分钟的配额，空闲后允许一次用完
+type LimitPolicy struct {
+	// RequestsPerMinute 每分钟请求数
+	RequestsPerMinute float64 `yaml:"requests_per_minute"`
+	// TokensPerMinute 每分钟近似 token 数，按 ApproxTokens 估算
+	TokensPerMinute float64 `yaml:"tokens_per_minute"`
+	// CharactersPerMinute 每分钟字符数（按 Unicode 字符计）
+	CharactersPerMinute float64 `yaml:"characters_per_minute"`
+	// MaxInFlight 同时进行的最大请求数
+	MaxInFlight int `yaml:"max_in_flight"`
+}
+
+// Enabled 是否设置了任一限制
+func (p LimitPolicy) Enabled() bool {
+	return p.RequestsPerMinute > 0 || p.TokensPerMinute > 0 || p.CharactersPerMinute > 0 || p.MaxInFlight > 0
+}
+
+// Validate 检查策略配置
+func (p LimitPolicy) Validate() error {
+	if p.RequestsPerMinute < 0 || p.TokensPerMinute < 0 || p.CharactersPerMinute < 0 || p.MaxInFlight < 0 {
+		return fmt.Errorf("%w: limits must not be negative", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// LimitEmbedder 客户端限流装饰器：按请求数、token 数、字符数限速，并限制并发请求数
+// 等待期间遵守 ctx 的取消和超时
+type LimitEmbedder struct {
+	inner Embedder
+
+	requests   *tokenBucket
+	tokens     *tokenBucket
+	characters *tokenBucket
+	// slots 并发请求的信号量，不限制并发时为 nil
+	slots chan struct{}
+}
+
+// NewLimitEmbedder 使用限流策略包装嵌入服务
+func NewLimitEmbedder(inner Embedder, policy LimitPolicy) *LimitEmbedder {
+	l := &LimitEmbedder{
+		inner:      inner,
+		requests:   newTokenBucket(policy.RequestsPerMinute),
+		tokens:     newTokenBucket(policy.TokensPerMinute),
+		characters: newTokenBucket(policy.CharactersPerMinute),
+	}
+	if policy.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, policy.MaxInFlight)
+	}
+	return l
+}
+
+// Embed 批量嵌入多个文本
+func (l *LimitEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	release, err := l.acquire(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.inner.Embed(ctx, texts)
+}
+
+// EmbedSingle 嵌入单个文本
+func (l *LimitEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	release, err := l.acquire(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.inner.EmbedSingle(ctx, text)
+}
+
+// BatchEmbed 分批处理大量文本，每批单独计入限流
+func (l *LimitEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	if batchSize <= 0 {
+		batchSize = len(texts)
+	}
+
+	var allEmbeddings [][]float32
+	for i := 0; i < len(texts); i += batchSize {
+		end := i + batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		embeddings, err := l.Embed(ctx, texts[i:end])
+		if err != nil {
+			return nil, err
+		}
+
+		allEmbeddings = append(allEmbeddings, embeddings...)
+	}
+
+	return allEmbeddings, nil
+}
+
+// EmbedQuery 以查询模式嵌入文本，内部服务不支持时直接嵌入
+func (l *LimitEmbedder) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	release, err := l.acquire(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return EmbedQuery(ctx, l.inner, query)
+}
+
+// EmbedDocuments 以文档模式嵌入多个文本，内部服务不支持时直接嵌入
+func (l *LimitEmbedder) EmbedDocuments(ctx context.Context, documents []string) ([][]float32, error) {
+	release, err := l.acquire(ctx, documents)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return EmbedDocuments(ctx, l.inner, documents)
+}
+
+// GetDimension 获取嵌入维度
+func (l *LimitEmbedder) GetDimension() int {
+	return l.inner.GetDimension()
+}
+
+// GetModel 获取模型名称
+func (l *LimitEmbedder) GetModel() string {
+	return l.inner.GetModel()
+}
+
+// Health 健康检查（不限流）
+func (l *LimitEmbedder) Health(ctx context.Context) error {
+	return l.inner.Health(ctx)
+}
+
+// acquire 等待并发名额和速率配额，返回释放并发名额的函数（私有方法）
+func (l *LimitEmbedder) acquire(ctx context.Context, texts []string) (func(), error) {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for concurrency limit: %w", ctx.Err())
+		}
+	}
+	release := func() {
+		if l.slots != nil {
+			<-l.slots
+		}
+	}
+
+	var tokens, chars int
+	for _, text := range texts {
+		tokens += ApproxTokens(text)
+		chars += utf8.RuneCountInString(text)
+	}
+
+	// 同时预留所有配额，按最长的等待时间等待，取消时归还
+	reservations := []struct {
+		bucket *tokenBucket
+		amount float64
+	}{
+		{l.requests, 1},
+		{l.tokens, float64(tokens)},
+		{l.characters, float64(chars)},
+	}
+	var wait time.Duration
+	for _, r := range reservations {
+		if d := r.bucket.reserve(r.amount); d > wait {
+			wait = d
+		}
+	}
+	if wait <= 0 {
+		return release, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return release, nil
+	case <-ctx.Done():
+		for _, r := range reservations {
+			r.bucket.cancel(r.amount)
+		}
+		release()
+		return nil, fmt.Errorf("waiting for rate limit: %w", ctx.Err())
+	}
+}
+
+// tokenBucket 令牌桶，允许预留超过当前余量的令牌（余量变为负数），后续请求顺延等待
+// perMinute 为 0 时不限制，所有方法都可在 nil 上调用
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64 // 每秒补充的令牌数
+	capacity float64
+	tokens   float64
+	last     time.Time
+	now      func() time.Time
+}
+
+// newTokenBucket 创建容量为一分钟配额的令牌桶，perMinute <= 0 时返回 nil
+func newTokenBucket(perMinute float64) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+	return &tokenBucket{
+		rate:     perMinute / 60,
+		capacity: perMinute,
+		tokens:   perMinute,
+		last:     time.Now(),
+		now:      time.Now,
+	}
+}
+
+// reserve 预留 n 个令牌，返回需要等待的时间
+func (b *tokenBucket) reserve(n float64) time.Duration {
+	if b == nil || n <= 0 {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	b.tokens -= n
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel 归还未使用的预留令牌
+func (b *tokenBucket) cancel(n float64) {
+	if b == nil || n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refill()
+	b.tokens += n
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+}
+
+// refill 按经过的时间补充令牌，调用方需持有锁（私有方法）
+func (b *tokenBucket) refill() {
+	now := b.now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+}
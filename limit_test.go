@@ -0,0 +1,129 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newTokenBucket(60) // 每秒 1 个，容量 60
+	b.now = func() time.Time { return now }
+	b.last = now
+
+	if d := b.reserve(60); d != 0 {
+		t.Errorf("Expected full bucket to allow 60, got wait %v", d)
+	}
+	if d := b.reserve(2); d != 2*time.Second {
+		t.Errorf("Expected 2s wait, got %v", d)
+	}
+	// 预留顺延：后续请求排在之前的预留之后
+	if d := b.reserve(1); d != 3*time.Second {
+		t.Errorf("Expected 3s wait, got %v", d)
+	}
+
+	b.cancel(3)
+	now = now.Add(10 * time.Second)
+	if d := b.reserve(10); d != 0 {
+		t.Errorf("Expected refilled bucket to allow 10, got wait %v", d)
+	}
+
+	now = now.Add(time.Hour)
+	if d := b.reserve(61); d != time.Second {
+		t.Errorf("Expected refill to be capped at capacity, got wait %v", d)
+	}
+
+	if newTokenBucket(0).reserve(1000) != 0 {
+		t.Error("Unlimited bucket should never wait")
+	}
+}
+
+func TestLimitEmbedderHonorsContext(t *testing.T) {
+	inner := &countingEmbedder{}
+	l := NewLimitEmbedder(inner, LimitPolicy{TokensPerMinute: 60})
+	long := strings.Repeat("word ", 80) // 约 100 token，超过一分钟配额
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := l.Embed(ctx, []string{long})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected wait to stop at deadline, took %v", elapsed)
+	}
+	if len(inner.received) != 0 {
+		t.Error("Request should not reach the provider while waiting")
+	}
+
+	// 取消的预留已归还，短文本可以立即通过
+	if _, err := l.EmbedSingle(context.Background(), "hi"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLimitEmbedderMaxInFlight(t *testing.T) {
+	inner := &taggedEmbedder{started: make(chan struct{}), release: make(chan struct{})}
+	l := NewLimitEmbedder(inner, LimitPolicy{MaxInFlight: 1})
+
+	done := make(chan error)
+	go func() {
+		_, err := l.EmbedSingle(context.Background(), "1")
+		done <- err
+	}()
+	<-inner.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := l.Embed(ctx, []string{"2"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected second request to wait for a slot, got %v", err)
+	}
+
+	close(inner.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	go func() { <-inner.started }()
+	if _, err := l.Embed(context.Background(), []string{"3"}); err != nil {
+		t.Errorf("Expected slot to be released, got %v", err)
+	}
+}
+
+func TestFactoryWrapsLimit(t *testing.T) {
+	var config Config
+	data := `
+provider: limit-mock
+limit:
+  requests_per_minute: 60
+  max_in_flight: 4
+`
+	if err := yaml.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatal(err)
+	}
+	if config.Limit.RequestsPerMinute != 60 || config.Limit.MaxInFlight != 4 {
+		t.Fatalf("Unexpected limit config: %+v", config.Limit)
+	}
+
+	factory := NewFactory()
+	factory.RegisterProvider("limit-mock", func(config Config) (Embedder, error) {
+		return &MockEmbedder{}, nil
+	})
+	e, err := factory.CreateWithConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e.(*LimitEmbedder); !ok {
+		t.Fatalf("Expected *LimitEmbedder, got %T", e)
+	}
+
+	config.Limit.MaxInFlight = -1
+	if _, err := factory.CreateWithConfig(config); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
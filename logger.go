@@ -1,14 +1,17 @@
 package embedder
 
 import (
-	"fmt"
-	"log"
-	"os"
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
 )
 
-// Logger 简化的日志接口，复制自zap的基础功能
+// Logger 组件日志记录器，输出到 log/slog
+// 未指定 *slog.Logger 时使用 SetDefaultLogger 设置的包级默认值，默认不输出任何日志
 type Logger struct {
 	name string
+	base *slog.Logger
 }
 
 // Field 日志字段
@@ -27,57 +30,114 @@ func Int(key string, value int) Field {
 	return Field{Key: key, Value: value}
 }
 
+// Duration 创建时长字段
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Value: value}
+}
+
 // Error 创建错误字段
 func Error(err error) Field {
 	return Field{Key: "error", Value: err}
 }
 
-// NewLogger 创建新的日志记录器
+// defaultLogger 包级默认日志，为 nil 时不输出（库默认静默）
+var defaultLogger atomic.Pointer[slog.Logger]
+
+// SetDefaultLogger 设置包级默认日志，影响所有未通过 Config.Logger 指定日志的组件
+// 传入 nil 恢复为静默
+func SetDefaultLogger(logger *slog.Logger) {
+	defaultLogger.Store(logger)
+}
+
+// NewLogger 创建使用包级默认日志的记录器
 func NewLogger(name string) *Logger {
 	return &Logger{name: name}
 }
 
+// NewSlogLogger 创建输出到指定 *slog.Logger 的记录器，logger 为 nil 时使用包级默认日志
+func NewSlogLogger(logger *slog.Logger, name string) *Logger {
+	return &Logger{name: name, base: logger}
+}
+
 // Named 创建命名的子日志记录器
 func (l *Logger) Named(name string) *Logger {
-	return &Logger{name: l.name + "." + name}
+	return &Logger{name: l.name + "." + name, base: l.base}
 }
 
 // Info 记录信息级别日志
 func (l *Logger) Info(msg string, fields ...Field) {
-	l.log("INFO", msg, fields...)
+	l.log(context.Background(), slog.LevelInfo, msg, fields)
 }
 
 // Error 记录错误级别日志
 func (l *Logger) Error(msg string, fields ...Field) {
-	l.log("ERROR", msg, fields...)
+	l.log(context.Background(), slog.LevelError, msg, fields)
 }
 
 // Debug 记录调试级别日志
 func (l *Logger) Debug(msg string, fields ...Field) {
-	if os.Getenv("DEBUG") != "" {
-		l.log("DEBUG", msg, fields...)
-	}
+	l.log(context.Background(), slog.LevelDebug, msg, fields)
 }
 
 // Warn 记录警告级别日志
 func (l *Logger) Warn(msg string, fields ...Field) {
-	l.log("WARN", msg, fields...)
-}
-
-// log 内部日志记录方法
-func (l *Logger) log(level, msg string, fields ...Field) {
-	logMsg := fmt.Sprintf("[%s] %s: %s", level, l.name, msg)
-	
-	if len(fields) > 0 {
-		logMsg += " {"
-		for i, field := range fields {
-			if i > 0 {
-				logMsg += ", "
-			}
-			logMsg += fmt.Sprintf("%s: %v", field.Key, field.Value)
-		}
-		logMsg += "}"
+	l.log(context.Background(), slog.LevelWarn, msg, fields)
+}
+
+// InfoContext 记录信息级别日志，附带 ctx 中的请求ID
+func (l *Logger) InfoContext(ctx context.Context, msg string, fields ...Field) {
+	l.log(ctx, slog.LevelInfo, msg, fields)
+}
+
+// ErrorContext 记录错误级别日志，附带 ctx 中的请求ID
+func (l *Logger) ErrorContext(ctx context.Context, msg string, fields ...Field) {
+	l.log(ctx, slog.LevelError, msg, fields)
+}
+
+// DebugContext 记录调试级别日志，附带 ctx 中的请求ID
+func (l *Logger) DebugContext(ctx context.Context, msg string, fields ...Field) {
+	l.log(ctx, slog.LevelDebug, msg, fields)
+}
+
+// WarnContext 记录警告级别日志，附带 ctx 中的请求ID
+func (l *Logger) WarnContext(ctx context.Context, msg string, fields ...Field) {
+	l.log(ctx, slog.LevelWarn, msg, fields)
+}
+
+// log 内部日志记录方法，级别未启用时不构造字段
+func (l *Logger) log(ctx context.Context, level slog.Level, msg string, fields []Field) {
+	logger := l.base
+	if logger == nil {
+		logger = defaultLogger.Load()
+	}
+	if logger == nil || !logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := make([]slog.Attr, 0, len(fields)+2)
+	attrs = append(attrs, slog.String("component", l.name))
+	if id, ok := RequestIDFromContext(ctx); ok {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+	for _, field := range fields {
+		attrs = append(attrs, slog.Any(field.Key, field.Value))
 	}
-	
-	log.Println(logMsg)
-}
\ No newline at end of file
+	logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// requestIDKey 请求ID在 context 中的键
+type requestIDKey struct{}
+
+// WithRequestID 在 ctx 中附带请求ID，之后的日志都会带上 request_id 字段
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext 取出 ctx 中的请求ID
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok && id != ""
+}
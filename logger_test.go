@@ -0,0 +1,121 @@
+package embedder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logEntries 解析 JSON 日志行
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Invalid JSON log line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLoggerSilentByDefault(t *testing.T) {
+	// 未设置任何日志时不应输出或 panic
+	logger := NewLogger("test")
+	logger.Info("hello", String("key", "value"))
+	logger.ErrorContext(WithRequestID(context.Background(), "id"), "failed", Error(ErrUnavailable))
+
+	var buf bytes.Buffer
+	SetDefaultLogger(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	defer SetDefaultLogger(nil)
+
+	logger.Info("filtered by level")
+	logger.Named("child").WarnContext(WithRequestID(context.Background(), "req-1"), "visible",
+		Int("count", 3), Duration("delay", time.Second), Error(ErrRateLimited))
+
+	entries := logEntries(t, &buf)
+	if len(entries) != 1 {
+		t.Fatalf("Expected 1 log entry, got %d: %s", len(entries), buf.String())
+	}
+	entry := entries[0]
+	if entry["msg"] != "visible" || entry["component"] != "test.child" || entry["request_id"] != "req-1" {
+		t.Errorf("Unexpected entry: %v", entry)
+	}
+	if entry["count"] != float64(3) || entry["error"] != ErrRateLimited.Error() {
+		t.Errorf("Unexpected fields: %v", entry)
+	}
+}
+
+func TestConfigLoggerReachesProvider(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": [][]float64{{1, 2, 3}}})
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	e, err := New("ollama").
+		WithBaseURL(server.URL).
+		WithModel("logger-test").
+		WithRetry(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}).
+		WithOption(OptionLazy, true).
+		WithLogger(logger).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	ctx := WithRequestID(context.Background(), "req-42")
+	if _, err := e.EmbedSingle(ctx, "hello"); err != nil {
+		t.Fatalf("EmbedSingle failed: %v", err)
+	}
+
+	var retried bool
+	for _, entry := range logEntries(t, &buf) {
+		if entry["level"] == "WARN" && entry["component"] == "ollama-embedder" {
+			retried = true
+			if entry["request_id"] != "req-42" || entry["attempt"] != float64(1) {
+				t.Errorf("Unexpected retry entry: %v", entry)
+			}
+		}
+	}
+	if !retried {
+		t.Errorf("Expected retry warning in provider logs: %s", buf.String())
+	}
+}
+
+func TestFactorySetLogger(t *testing.T) {
+	var buf bytes.Buffer
+	factory := NewFactory()
+	factory.SetLogger(slog.New(slog.NewJSONHandler(&buf, nil)))
+	factory.RegisterProvider("log-mock", func(config Config) (Embedder, error) {
+		if config.Logger == nil {
+			t.Error("Expected factory logger to be passed through Config")
+		}
+		return &MockEmbedder{}, nil
+	})
+
+	if _, err := factory.CreateWithConfig(Config{Provider: "log-mock", Model: "m"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"component":"embedder-factory"`) {
+		t.Errorf("Expected factory log output, got %s", buf.String())
+	}
+}
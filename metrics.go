@@ -0,0 +1,221 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// 指标名称，MetricsEmbedder 记录的所有指标都带 provider、model、method 标签
+const (
+	// MetricRequests 请求次数
+	MetricRequests = "embedder_requests_total"
+	// MetricTexts 嵌入的文本数
+	MetricTexts = "embedder_texts_total"
+	// MetricCharacters 嵌入的字符数（按 Unicode 字符计）
+	MetricCharacters = "embedder_characters_total"
+	// MetricTokens 嵌入的近似 token 数，按 ApproxTokens 估算
+	MetricTokens = "embedder_tokens_total"
+	// MetricErrors 失败的请求数，额外带 kind 标签
+	MetricErrors = "embedder_errors_total"
+	// MetricLatency 请求耗时（秒）
+	MetricLatency = "embedder_request_duration_seconds"
+	// MetricBatchSize 每次请求的文本数
+	MetricBatchSize = "embedder_batch_size"
+	// MetricCacheHits 缓存命中次数，内部服务为 CacheEmbedder 时记录
+	MetricCacheHits = "embedder_cache_hits_total"
+	// MetricCacheMisses 缓存未命中次数，内部服务为 CacheEmbedder 时记录
+	MetricCacheMisses = "embedder_cache_misses_total"
+)
+
+// Labels 指标标签
+type Labels map[string]string
+
+// Metrics 指标记录接口，实现需要并发安全
+// 内置实现为 Registry，也可以适配到 Prometheus client、OpenTelemetry 等
+type Metrics interface {
+	// AddCounter 计数器增加 delta
+	AddCounter(name string, labels Labels, delta float64)
+
+	// ObserveHistogram 直方图记录一次观测值
+	ObserveHistogram(name string, labels Labels, value float64)
+}
+
+// cacheStatser 提供缓存命中统计的嵌入服务，如 CacheEmbedder
+type cacheStatser interface {
+	Stats() (hits, misses int64)
+}
+
+// MetricsEmbedder 记录请求数、文本量、耗时和错误的装饰器
+// 包装 CacheEmbedder 时同时记录缓存命中情况
+type MetricsEmbedder struct {
+	inner    Embedder
+	metrics  Metrics
+	provider string
+
+	// 上次上报时的缓存统计，用于计算增量
+	reportedHits   atomic.Int64
+	reportedMisses atomic.Int64
+}
+
+// NewMetricsEmbedder 使用指标记录包装嵌入服务，provider 作为标签
+//
+//	registry := embedder.NewRegistry()
+//	e = embedder.NewMetricsEmbedder(embedder.NewCacheEmbedder(e, store, "ollama"), registry, "ollama")
+//	http.Handle("/metrics", registry.Handler())
+func NewMetricsEmbedder(inner Embedder, metrics Metrics, provider string) *MetricsEmbedder {
+	m := &MetricsEmbedder{inner: inner, metrics: metrics, provider: provider}
+	if stats, ok := inner.(cacheStatser); ok {
+		// 只统计包装之后的缓存访问
+		hits, misses := stats.Stats()
+		m.reportedHits.Store(hits)
+		m.reportedMisses.Store(misses)
+	}
+	return m
+}
+
+// Embed 批量嵌入多个文本
+func (m *MetricsEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	start := time.Now()
+	embeddings, err := m.inner.Embed(ctx, texts)
+	m.record("embed", texts, start, err)
+	return embeddings, err
+}
+
+// EmbedSingle 嵌入单个文本
+func (m *MetricsEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	start := time.Now()
+	embedding, err := m.inner.EmbedSingle(ctx, text)
+	m.record("embed_single", []string{text}, start, err)
+	return embedding, err
+}
+
+// BatchEmbed 分批处理大量文本
+func (m *MetricsEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	start := time.Now()
+	embeddings, err := m.inner.BatchEmbed(ctx, texts, batchSize)
+	m.record("batch_embed", texts, start, err)
+	return embeddings, err
+}
+
+// EmbedQuery 以查询模式嵌入文本，内部服务不支持时直接嵌入
+func (m *MetricsEmbedder) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	start := time.Now()
+	embedding, err := EmbedQuery(ctx, m.inner, query)
+	m.record("embed_query", []string{query}, start, err)
+	return embedding, err
+}
+
+// EmbedDocuments 以文档模式嵌入多个文本，内部服务不支持时直接嵌入
+func (m *MetricsEmbedder) EmbedDocuments(ctx context.Context, documents []string) ([][]float32, error) {
+	start := time.Now()
+	embeddings, err := EmbedDocuments(ctx, m.inner, documents)
+	m.record("embed_documents", documents, start, err)
+	return embeddings, err
+}
+
+// GetDimension 获取嵌入维度
+func (m *MetricsEmbedder) GetDimension() int {
+	return m.inner.GetDimension()
+}
+
+// GetModel 获取模型名称
+func (m *MetricsEmbedder) GetModel() string {
+	return m.inner.GetModel()
+}
+
+// Health 健康检查，失败时记录到 method="health" 的错误计数
+func (m *MetricsEmbedder) Health(ctx context.Context) error {
+	err := m.inner.Health(ctx)
+	if err != nil {
+		labels := m.labels("health")
+		labels["kind"] = ErrorKind(err)
+		m.metrics.AddCounter(MetricErrors, labels, 1)
+	}
+	return err
+}
+
+// record 记录一次请求的指标（私有方法）
+func (m *MetricsEmbedder) record(method string, texts []string, start time.Time, err error) {
+	labels := m.labels(method)
+
+	m.metrics.AddCounter(MetricRequests, labels, 1)
+	m.metrics.ObserveHistogram(MetricLatency, labels, time.Since(start).Seconds())
+	m.metrics.ObserveHistogram(MetricBatchSize, labels, float64(len(texts)))
+
+	if err != nil {
+		errorLabels := m.labels(method)
+		errorLabels["kind"] = ErrorKind(err)
+		m.metrics.AddCounter(MetricErrors, errorLabels, 1)
+	} else {
+		var chars, tokens int
+		for _, text := range texts {
+			chars += utf8.RuneCountInString(text)
+			tokens += ApproxTokens(text)
+		}
+		m.metrics.AddCounter(MetricTexts, labels, float64(len(texts)))
+		m.metrics.AddCounter(MetricCharacters, labels, float64(chars))
+		m.metrics.AddCounter(MetricTokens, labels, float64(tokens))
+	}
+
+	if stats, ok := m.inner.(cacheStatser); ok {
+		hits, misses := stats.Stats()
+		cacheLabels := Labels{"provider": m.provider, "model": m.inner.GetModel()}
+		if delta := advance(&m.reportedHits, hits); delta > 0 {
+			m.metrics.AddCounter(MetricCacheHits, cacheLabels, float64(delta))
+		}
+		if delta := advance(&m.reportedMisses, misses); delta > 0 {
+			m.metrics.AddCounter(MetricCacheMisses, cacheLabels, float64(delta))
+		}
+	}
+}
+
+// labels 基础标签（私有方法）
+func (m *MetricsEmbedder) labels(method string) Labels {
+	return Labels{"provider": m.provider, "model": m.inner.GetModel(), "method": method}
+}
+
+// advance 将 reported 推进到 current 并返回增量，并发调用时每个增量只会被上报一次
+func advance(reported *atomic.Int64, current int64) int64 {
+	for {
+		prev := reported.Load()
+		if current <= prev {
+			return 0
+		}
+		if reported.CompareAndSwap(prev, current) {
+			return current - prev
+		}
+	}
+}
+
+// ErrorKind 返回错误分类的简短名称，用作指标和日志标签
+func ErrorKind(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrModelNotFound):
+		return "model_not_found"
+	case errors.Is(err, ErrInputTooLong):
+		return "input_too_long"
+	case errors.Is(err, ErrInvalidResponse):
+		return "invalid_response"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrInvalidConfig):
+		return "invalid_config"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,287 @@
+package embedder
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLatencyBuckets 耗时直方图的默认分桶（秒）
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// DefaultSizeBuckets 批大小直方图的默认分桶
+var DefaultSizeBuckets = []float64{1, 2, 4, 8, 16, 32, 64, 128, 256, 512, 1024}
+
+// metricHelp 内置指标的说明，输出到 Prometheus 的 # HELP
+var metricHelp = map[string]string{
+	MetricRequests:    "Number of embedding requests.",
+	MetricTexts:       "Number of texts embedded.",
+	MetricCharacters:  "Number of characters embedded.",
+	MetricTokens:      "Approximate number of tokens embedded.",
+	MetricErrors:      "Number of failed requests by error kind.",
+	MetricLatency:     "Embedding request latency in seconds.",
+	MetricBatchSize:   "Number of texts per embedding request.",
+	MetricCacheHits:   "Number of embedding cache hits.",
+	MetricCacheMisses: "Number of embedding cache misses.",
+}
+
+// Registry 进程内指标注册表，实现 Metrics
+// 可通过 Handler 以 Prometheus 文本格式导出，或通过 Expvar 发布到 /debug/vars
+type Registry struct {
+	mu         sync.Mutex
+	counters   map[string]map[string]*counterSeries
+	histograms map[string]map[string]*histogramSeries
+	buckets    map[string][]float64
+}
+
+// counterSeries 一组标签下的计数器
+type counterSeries struct {
+	labels string
+	value  float64
+}
+
+// histogramSeries 一组标签下的直方图
+type histogramSeries struct {
+	labels  string
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// NewRegistry 创建指标注册表
+func NewRegistry() *Registry {
+	return &Registry{
+		counters:   make(map[string]map[string]*counterSeries),
+		histograms: make(map[string]map[string]*histogramSeries),
+		buckets: map[string][]float64{
+			MetricBatchSize: DefaultSizeBuckets,
+		},
+	}
+}
+
+// SetBuckets 设置直方图的分桶上界，只影响之后新建的序列；未设置时使用 DefaultLatencyBuckets
+func (r *Registry) SetBuckets(name string, buckets []float64) {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buckets[name] = sorted
+}
+
+// AddCounter 计数器增加 delta
+func (r *Registry) AddCounter(name string, labels Labels, delta float64) {
+	key := formatLabels(labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	series, ok := r.counters[name]
+	if !ok {
+		series = make(map[string]*counterSeries)
+		r.counters[name] = series
+	}
+	counter, ok := series[key]
+	if !ok {
+		counter = &counterSeries{labels: key}
+		series[key] = counter
+	}
+	counter.value += delta
+}
+
+// ObserveHistogram 直方图记录一次观测值
+func (r *Registry) ObserveHistogram(name string, labels Labels, value float64) {
+	key := formatLabels(labels)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	series, ok := r.histograms[name]
+	if !ok {
+		series = make(map[string]*histogramSeries)
+		r.histograms[name] = series
+	}
+	histogram, ok := series[key]
+	if !ok {
+		buckets := r.bucketsFor(name)
+		histogram = &histogramSeries{labels: key, buckets: buckets, counts: make([]uint64, len(buckets))}
+		series[key] = histogram
+	}
+
+	for i, bound := range histogram.buckets {
+		if value <= bound {
+			histogram.counts[i]++
+		}
+	}
+	histogram.sum += value
+	histogram.count++
+}
+
+// Counter 读取计数器当前值，不存在时为 0
+func (r *Registry) Counter(name string, labels Labels) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if counter, ok := r.counters[name][formatLabels(labels)]; ok {
+		return counter.value
+	}
+	return 0
+}
+
+// HistogramCount 读取直方图的观测次数与总和
+func (r *Registry) HistogramCount(name string, labels Labels) (count uint64, sum float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if histogram, ok := r.histograms[name][formatLabels(labels)]; ok {
+		return histogram.count, histogram.sum
+	}
+	return 0, 0
+}
+
+// WritePrometheus 以 Prometheus 文本格式（0.0.4）输出所有指标
+func (r *Registry) WritePrometheus(out io.Writer) error {
+	w := bufio.NewWriter(out)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range sortedKeys(r.counters) {
+		writeMetricHeader(w, name, "counter")
+		series := r.counters[name]
+		for _, key := range sortedKeys(series) {
+			fmt.Fprintf(w, "%s%s %s\n", name, series[key].labels, formatFloat(series[key].value))
+		}
+	}
+
+	for _, name := range sortedKeys(r.histograms) {
+		writeMetricHeader(w, name, "histogram")
+		series := r.histograms[name]
+		for _, key := range sortedKeys(series) {
+			histogram := series[key]
+			for i, bound := range histogram.buckets {
+				fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(histogram.labels, "le", formatFloat(bound)), histogram.counts[i])
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(histogram.labels, "le", "+Inf"), histogram.count)
+			fmt.Fprintf(w, "%s_sum%s %s\n", name, histogram.labels, formatFloat(histogram.sum))
+			fmt.Fprintf(w, "%s_count%s %d\n", name, histogram.labels, histogram.count)
+		}
+	}
+	return w.Flush()
+}
+
+// Handler 返回输出 Prometheus 文本格式的 HTTP handler，通常挂载到 /metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WritePrometheus(w)
+	})
+}
+
+// Expvar 返回指标快照的 expvar.Var，可通过 expvar.Publish 发布到 /debug/vars
+// 计数器为 {"名称{标签}": 值}，直方图为 {"名称{标签}": {"count": n, "sum": s}}
+func (r *Registry) Expvar() expvar.Var {
+	return expvar.Func(func() interface{} {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		snapshot := make(map[string]interface{})
+		for name, series := range r.counters {
+			for key, counter := range series {
+				snapshot[name+key] = counter.value
+			}
+		}
+		for name, series := range r.histograms {
+			for key, histogram := range series {
+				snapshot[name+key] = map[string]interface{}{"count": histogram.count, "sum": histogram.sum}
+			}
+		}
+		return snapshot
+	})
+}
+
+// PublishExpvar 以 name 发布到 expvar，同名变量已存在时 expvar 会 panic
+func (r *Registry) PublishExpvar(name string) {
+	expvar.Publish(name, r.Expvar())
+}
+
+// bucketsFor 返回直方图的分桶，调用方需持有锁（私有方法）
+func (r *Registry) bucketsFor(name string) []float64 {
+	if buckets, ok := r.buckets[name]; ok {
+		return buckets
+	}
+	return DefaultLatencyBuckets
+}
+
+// writeMetricHeader 输出 # HELP 和 # TYPE
+func writeMetricHeader(w *bufio.Writer, name, kind string) {
+	if help, ok := metricHelp[name]; ok {
+		fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	}
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+// formatLabels 将标签按名称排序后格式化为 {a="1",b="2"}，无标签时为空串
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `%s="%s"`, name, labelEscaper.Replace(labels[name]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// labelEscaper 按 Prometheus 文本格式转义标签值
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// withLabel 在已格式化的标签后追加一个标签
+func withLabel(labels, name, value string) string {
+	pair := fmt.Sprintf(`%s="%s"`, name, value)
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+// formatFloat 按 Prometheus 的格式输出浮点数
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// sortedKeys 返回按字母顺序排列的键
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,147 @@
+package embedder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// errorEmbedder 总是返回指定错误的测试嵌入服务
+type errorEmbedder struct {
+	MockEmbedder
+	err error
+}
+
+func (e *errorEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return nil, e.err
+}
+
+func TestMetricsEmbedderCountsRequests(t *testing.T) {
+	registry := NewRegistry()
+	inner := &countingEmbedder{}
+	e := NewMetricsEmbedder(NewCacheEmbedder(inner, NewMemoryCache(10), "mock"), registry, "mock")
+	ctx := context.Background()
+
+	if _, err := e.Embed(ctx, []string{"a", "bb"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.BatchEmbed(ctx, []string{"a", "你好"}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	embed := Labels{"provider": "mock", "model": "mock-model", "method": "embed"}
+	batch := Labels{"provider": "mock", "model": "mock-model", "method": "batch_embed"}
+	cache := Labels{"provider": "mock", "model": "mock-model"}
+
+	checks := []struct {
+		name   string
+		labels Labels
+		want   float64
+	}{
+		{MetricRequests, embed, 1},
+		{MetricRequests, batch, 1},
+		{MetricTexts, embed, 2},
+		{MetricCharacters, embed, 3},
+		{MetricCharacters, batch, 3},
+		{MetricTokens, batch, 3},
+		{MetricCacheHits, cache, 1},
+		{MetricCacheMisses, cache, 3},
+	}
+	for _, c := range checks {
+		if got := registry.Counter(c.name, c.labels); got != c.want {
+			t.Errorf("%s%v: expected %v, got %v", c.name, c.labels, c.want, got)
+		}
+	}
+
+	if count, sum := registry.HistogramCount(MetricBatchSize, embed); count != 1 || sum != 2 {
+		t.Errorf("Expected one batch of size 2, got count %d sum %v", count, sum)
+	}
+	if count, _ := registry.HistogramCount(MetricLatency, batch); count != 1 {
+		t.Errorf("Expected one latency observation, got %d", count)
+	}
+}
+
+func TestMetricsEmbedderErrors(t *testing.T) {
+	registry := NewRegistry()
+	err := &ProviderError{Provider: "mock", StatusCode: 429, Index: -1, Kind: ErrRateLimited}
+	e := NewMetricsEmbedder(&errorEmbedder{err: err}, registry, "mock")
+
+	if _, got := e.Embed(context.Background(), []string{"a"}); got != err {
+		t.Fatalf("Expected error to pass through, got %v", got)
+	}
+
+	labels := Labels{"provider": "mock", "model": "mock-model", "method": "embed"}
+	if registry.Counter(MetricRequests, labels) != 1 || registry.Counter(MetricTexts, labels) != 0 {
+		t.Error("Failed requests should count as requests but not texts")
+	}
+	labels["kind"] = "rate_limited"
+	if registry.Counter(MetricErrors, labels) != 1 {
+		t.Error("Expected rate_limited error to be counted")
+	}
+
+	kinds := map[error]string{
+		context.Canceled:                    "canceled",
+		fmt.Errorf("x: %w", ErrUnavailable): "unavailable",
+		ErrInputTooLong:                     "input_too_long",
+		fmt.Errorf("plain"):                 "other",
+	}
+	for err, want := range kinds {
+		if got := ErrorKind(err); got != want {
+			t.Errorf("ErrorKind(%v) = %s, want %s", err, got, want)
+		}
+	}
+}
+
+func TestMetricsEmbedderKeepsQueryMode(t *testing.T) {
+	inner := &countingEmbedder{}
+	e := NewMetricsEmbedder(NewInstructionEmbedder(inner, PromptTemplate{Query: "q: "}), NewRegistry(), "mock")
+
+	if _, err := EmbedQuery(context.Background(), e, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.received) != 1 || inner.received[0] != "q: hello" {
+		t.Errorf("Expected query prefix to be applied, got %v", inner.received)
+	}
+}
+
+func TestRegistryExport(t *testing.T) {
+	registry := NewRegistry()
+	labels := Labels{"provider": "p", "model": `we"ird`}
+	registry.AddCounter(MetricRequests, labels, 2)
+	registry.ObserveHistogram(MetricBatchSize, labels, 3)
+	registry.ObserveHistogram(MetricLatency, nil, 0.2)
+
+	rec := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		"# TYPE embedder_requests_total counter",
+		`embedder_requests_total{model="we\"ird",provider="p"} 2`,
+		"# TYPE embedder_batch_size histogram",
+		`embedder_batch_size_bucket{model="we\"ird",provider="p",le="2"} 0`,
+		`embedder_batch_size_bucket{model="we\"ird",provider="p",le="4"} 1`,
+		`embedder_batch_size_bucket{model="we\"ird",provider="p",le="+Inf"} 1`,
+		`embedder_batch_size_sum{model="we\"ird",provider="p"} 3`,
+		`embedder_request_duration_seconds_bucket{le="0.25"} 1`,
+		"embedder_request_duration_seconds_count 1",
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("Expected %q in output:\n%s", want, body)
+		}
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected content type %q", rec.Header().Get("Content-Type"))
+	}
+
+	var snapshot map[string]interface{}
+	if err := json.Unmarshal([]byte(registry.Expvar().String()), &snapshot); err != nil {
+		t.Fatalf("Expvar output is not JSON: %v", err)
+	}
+	if snapshot[`embedder_requests_total{model="we\"ird",provider="p"}`] != float64(2) {
+		t.Errorf("Unexpected expvar snapshot: %v", snapshot)
+	}
+}
@@ -0,0 +1,212 @@
+package embedder
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// SimilarityMetric 模型推荐的相似度度量
+type SimilarityMetric string
+
+const (
+	// SimilarityCosine 余弦相似度
+	SimilarityCosine SimilarityMetric = "cosine"
+	// SimilarityDot 点积
+	SimilarityDot SimilarityMetric = "dot"
+	// SimilarityEuclidean 欧氏距离
+	SimilarityEuclidean SimilarityMetric = "euclidean"
+)
+
+// ModelMetadata 嵌入模型的能力信息，未知的字段保持零值
+type ModelMetadata struct {
+	// Name 模型名，无精确匹配时按最长前缀匹配，例如 "e5-large" 匹配 "e5-large-v2"
+	Name string `yaml:"name"`
+	// Dimension 输出维度，已知时 provider 跳过维度探测
+	Dimension int `yaml:"dimension"`
+	// MaxTokens 最大输入 token 数，只在设置了 overflow.mode 时用于检查输入
+	MaxTokens int `yaml:"max_tokens"`
+	// Similarity 推荐的相似度度量
+	Similarity SimilarityMetric `yaml:"similarity"`
+	// Normalized 输出是否已经是单位向量
+	Normalized bool `yaml:"normalized"`
+	// Prompt 查询与文档前缀
+	Prompt PromptTemplate `yaml:"prompt"`
+}
+
+// bgeQueryInstruction bge、mxbai 等模型的英文查询指令
+const bgeQueryInstruction = "Represent this sentence for searching relevant passages: "
+
+// e5InstructQuery e5 instruct 系列的查询指令，文档不加前缀
+const e5InstructQuery = "Instruct: Given a web search query, retrieve relevant passages that answer the query\nQuery: "
+
+// builtinModels 内置的常见模型信息
+var builtinModels = []ModelMetadata{
+	{Name: "nomic-embed-text", Dimension: 768, MaxTokens: 8192, Similarity: SimilarityCosine,
+		Prompt: PromptTemplate{Query: "search_query: ", Document: "search_document: "}},
+	{Name: "mxbai-embed-large", Dimension: 1024, MaxTokens: 512, Similarity: SimilarityCosine,
+		Prompt: PromptTemplate{Query: bgeQueryInstruction}},
+	{Name: "snowflake-arctic-embed", MaxTokens: 512, Similarity: SimilarityCosine,
+		Prompt: PromptTemplate{Query: bgeQueryInstruction}},
+	{Name: "all-minilm", Dimension: 384, MaxTokens: 256, Similarity: SimilarityCosine},
+	{Name: "bge-m3", Dimension: 1024, MaxTokens: 8192, Similarity: SimilarityCosine, Normalized: true},
+	{Name: "bge-small-en", Dimension: 384, MaxTokens: 512, Similarity: SimilarityCosine, Normalized: true,
+		Prompt: PromptTemplate{Query: bgeQueryInstruction}},
+	{Name: "bge-base-en", Dimension: 768, MaxTokens: 512, Similarity: SimilarityCosine, Normalized: true,
+		Prompt: PromptTemplate{Query: bgeQueryInstruction}},
+	{Name: "bge-large-en", Dimension: 1024, MaxTokens: 512, Similarity: SimilarityCosine, Normalized: true,
+		Prompt: PromptTemplate{Query: bgeQueryInstruction}},
+	// e5 只按 small/base/large 系列做前缀匹配，e5-mistral 等 instruct 模型的格式和长度不同
+	{Name: "e5-small", Dimension: 384, MaxTokens: 512, Similarity: SimilarityCosine,
+		Prompt: PromptTemplate{Query: "query: ", Document: "passage: "}},
+	{Name: "e5-base", Dimension: 768, MaxTokens: 512, Similarity: SimilarityCosine,
+		Prompt: PromptTemplate{Query: "query: ", Document: "passage: "}},
+	{Name: "e5-large", Dimension: 1024, MaxTokens: 512, Similarity: SimilarityCosine,
+		Prompt: PromptTemplate{Query: "query: ", Document: "passage: "}},
+	{Name: "e5-small-v2", Dimension: 384, MaxTokens: 512, Similarity: SimilarityCosine,
+		Prompt: PromptTemplate{Query: "query: ", Document: "passage: "}},
+	{Name: "e5-base-v2", Dimension: 768, MaxTokens: 512, Similarity: SimilarityCosine,
+		Prompt: PromptTemplate{Query: "query: ", Document: "passage: "}},
+	{Name: "e5-large-v2", Dimension: 1024, MaxTokens: 512, Similarity: SimilarityCosine,
+		Prompt: PromptTemplate{Query: "query: ", Document: "passage: "}},
+	{Name: "multilingual-e5-small", Dimension: 384, MaxTokens: 512, Similarity: SimilarityCosine,
+		Prompt: PromptTemplate{Query: "query: ", Document: "passage: "}},
+	{Name: "multilingual-e5-base", Dimension: 768, MaxTokens: 512, Similarity: SimilarityCosine,
+		Prompt: PromptTemplate{Query: "query: ", Document: "passage: "}},
+	{Name: "multilingual-e5-large", Dimension: 1024, MaxTokens: 512, Similarity: SimilarityCosine,
+		Prompt: PromptTemplate{Query: "query: ", Document: "passage: "}},
+	{Name: "multilingual-e5-large-instruct", Dimension: 1024, MaxTokens: 512, Similarity: SimilarityCosine,
+		Prompt: PromptTemplate{Query: e5InstructQuery}},
+	{Name: "e5-mistral-7b-instruct", Dimension: 4096, MaxTokens: 32768, Similarity: SimilarityCosine,
+		Prompt: PromptTemplate{Query: e5InstructQuery}},
+	{Name: "text-embedding-3-small", Dimension: 1536, MaxTokens: 8191, Similarity: SimilarityCosine, Normalized: true},
+	{Name: "text-embedding-3-large", Dimension: 3072, MaxTokens: 8191, Similarity: SimilarityCosine, Normalized: true},
+	{Name: "text-embedding-ada-002", Dimension: 1536, MaxTokens: 8191, Similarity: SimilarityCosine, Normalized: true},
+}
+
+// modelRegistry 模型信息注册表，键为规范化后的模型名
+var (
+	modelRegistry   = make(map[string]ModelMetadata)
+	modelRegistryMu sync.RWMutex
+)
+
+func init() {
+	for _, model := range builtinModels {
+		modelRegistry[modelNameWithTag(model.Name)] = model
+	}
+}
+
+// RegisterModel 注册或覆盖模型信息
+func RegisterModel(model ModelMetadata) error {
+	if strings.TrimSpace(model.Name) == "" {
+		return fmt.Errorf("%w: model name is required", ErrInvalidConfig)
+	}
+
+	modelRegistryMu.Lock()
+	defer modelRegistryMu.Unlock()
+	modelRegistry[modelNameWithTag(model.Name)] = model
+	return nil
+}
+
+// ModelInfo 查询模型信息
+// 忽略大小写、组织名（intfloat/）和 Ollama 标签（:latest），无精确匹配时使用最长前缀匹配
+func ModelInfo(model string) (ModelMetadata, bool) {
+	modelRegistryMu.RLock()
+	defer modelRegistryMu.RUnlock()
+
+	// 带标签的精确匹配优先，例如 snowflake-arctic-embed:22m
+	if info, ok := modelRegistry[modelNameWithTag(model)]; ok {
+		return info, true
+	}
+
+	name := baseModelName(model)
+	if info, ok := modelRegistry[name]; ok {
+		return info, true
+	}
+
+	keys := make([]string, 0, len(modelRegistry))
+	for key := range modelRegistry {
+		if strings.HasPrefix(name, key) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return ModelMetadata{}, false
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	return modelRegistry[keys[0]], true
+}
+
+// ListModels 列出所有已注册的模型名
+func ListModels() []string {
+	modelRegistryMu.RLock()
+	defer modelRegistryMu.RUnlock()
+
+	models := make([]string, 0, len(modelRegistry))
+	for _, info := range modelRegistry {
+		models = append(models, info.Name)
+	}
+	sort.Strings(models)
+	return models
+}
+
+// modelRegistryFile 模型信息YAML文件格式
+type modelRegistryFile struct {
+	Models []ModelMetadata `yaml:"models"`
+}
+
+// LoadModelRegistry 从YAML文件加载模型信息并注册，覆盖同名的内置条目
+//
+//	models:
+//	  - name: my-embed-model
+//	    dimension: 1024
+//	    max_tokens: 512
+//	    similarity: cosine
+//	    prompt:
+//	      query: "query: "
+func LoadModelRegistry(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var file modelRegistryFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return err
+	}
+
+	for _, model := range file.Models {
+		if err := RegisterModel(model); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modelDimension 返回已知的模型维度，未知时为 0
+func modelDimension(model string) int {
+	info, _ := ModelInfo(model)
+	return info.Dimension
+}
+
+// baseModelName 去掉 Ollama 标签（:latest）和组织名（intfloat/），统一小写
+func baseModelName(model string) string {
+	name := modelNameWithTag(model)
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// modelNameWithTag 去掉组织名并统一小写，保留 Ollama 标签
+func modelNameWithTag(model string) string {
+	name := strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.TrimSuffix(name, ":latest")
+}
@@ -0,0 +1,122 @@
+package embedder
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestModelInfo(t *testing.T) {
+	cases := []struct {
+		model     string
+		dimension int
+		found     bool
+	}{
+		{"nomic-embed-text", 768, true},
+		{"nomic-embed-text:latest", 768, true},
+		{"intfloat/multilingual-e5-large", 1024, true},
+		{"intfloat/e5-large", 1024, true},
+		{"intfloat/e5-mistral-7b-instruct", 4096, true},
+		{"multilingual-e5-large-instruct", 1024, true},
+		{"e5-unknown-variant", 0, false},
+		{"BAAI/bge-m3", 1024, true},
+		{"qwen2.5:7b", 0, false},
+	}
+	for _, c := range cases {
+		info, found := ModelInfo(c.model)
+		if found != c.found || info.Dimension != c.dimension {
+			t.Errorf("ModelInfo(%q) = %+v, %v", c.model, info, found)
+		}
+	}
+}
+
+func TestModelInfoInstructVariants(t *testing.T) {
+	// instruct 模型不能套用 e5 系列的 query:/passage: 前缀和 512 长度
+	for _, model := range []string{"e5-mistral-7b-instruct", "multilingual-e5-large-instruct"} {
+		info, _ := ModelInfo(model)
+		if info.Prompt.Query != e5InstructQuery || info.Prompt.Document != "" {
+			t.Errorf("Unexpected prompt for %s: %+v", model, info.Prompt)
+		}
+	}
+	if info, _ := ModelInfo("e5-mistral-7b-instruct"); info.MaxTokens != 32768 {
+		t.Errorf("Expected 32k context for e5-mistral, got %d", info.MaxTokens)
+	}
+}
+
+func TestLoadModelRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "models.yaml")
+	os.WriteFile(path, []byte(`models:
+  - name: custom-embed:small
+    dimension: 256
+    max_tokens: 128
+    similarity: dot
+    prompt:
+      query: "q: "
+`), 0o644)
+
+	if err := LoadModelRegistry(path); err != nil {
+		t.Fatalf("LoadModelRegistry failed: %v", err)
+	}
+
+	info, ok := ModelInfo("custom-embed:small")
+	if !ok || info.Dimension != 256 || info.Similarity != SimilarityDot || info.Prompt.Query != "q: " {
+		t.Errorf("Unexpected model info: %+v", info)
+	}
+	if _, ok := ModelInfo("custom-embed:large"); ok {
+		t.Error("Tagged registration should not match other tags")
+	}
+}
+
+func TestOllamaEmbedderSkipsProbeForKnownModel(t *testing.T) {
+	var embedRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/version":
+			w.WriteHeader(http.StatusOK)
+		default:
+			embedRequests++
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	embedder, err := NewOllamaEmbedder(Config{BaseURL: server.URL, Model: "nomic-embed-text", Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("Failed to create OllamaEmbedder: %v", err)
+	}
+	if embedder.GetDimension() != 768 || embedRequests != 0 {
+		t.Errorf("Expected dimension 768 without probing, got %d after %d requests", embedder.GetDimension(), embedRequests)
+	}
+}
+
+func TestFactoryUsesModelMaxTokens(t *testing.T) {
+	inner := &countingEmbedder{}
+	factory := NewFactory()
+	factory.RegisterProvider("mock", func(config Config) (Embedder, error) {
+		return inner, nil
+	})
+
+	embedder, err := factory.CreateWithConfig(Config{
+		Provider: "mock",
+		Model:    "all-minilm",
+		Overflow: OverflowPolicy{Mode: OverflowTruncateTail},
+	})
+	if err != nil {
+		t.Fatalf("CreateWithConfig failed: %v", err)
+	}
+
+	long := make([]byte, 4096)
+	for i := range long {
+		long[i] = 'a'
+	}
+	if _, err := embedder.Embed(context.Background(), []string{string(long)}); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if got := len(inner.received[0]); got != 1024 {
+		t.Errorf("Expected input truncated to 256 tokens (1024 chars), got %d chars", got)
+	}
+}
@@ -4,35 +4,78 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Ollama 的选项键
+const (
+	// OptionConcurrency 并发请求数，Ollama 开启多个并行槽位时可提高吞吐，默认 1
+	OptionConcurrency = "concurrency"
+	// OptionLazy 延迟初始化，创建时不访问网络，维度在首次嵌入或 Init 时确定
+	OptionLazy = "lazy"
+	// OptionDimension 已知的嵌入维度，设置后跳过维度检测
+	OptionDimension = "dimension"
 )
 
 // OllamaEmbedder Ollama嵌入服务实现
 type OllamaEmbedder struct {
-	baseURL    string
-	model      string
-	httpClient *http.Client
-	dimension  int
-	logger     *Logger
+	baseURL     string
+	model       string
+	httpClient  *http.Client
+	concurrency int
+	retry       RetryPolicy
+	logger      *Logger
+	tracer      Tracer
+	// slots 限制同时进行的 HTTP 请求数为 concurrency，BatchEmbed 与旧版接口的并发共用该上限
+	slots chan struct{}
+
+	// dimension 嵌入维度，延迟初始化时在首次嵌入后确定
+	dimension atomic.Int64
+	// verified 维度已与实际响应核对；选项或注册表给出的维度在首次响应时核对并更正
+	verified    atomic.Bool
+	dimensionMu sync.Mutex
+	// legacy 服务端不支持 /api/embed 时切换到旧版 /api/embeddings
+	legacy atomic.Bool
 }
 
-// ollamaEmbedRequest Ollama嵌入请求格式
+// ollamaEmbedRequest Ollama嵌入请求格式（旧版 /api/embeddings）
 type ollamaEmbedRequest struct {
 	Model  string `json:"model"`
 	Prompt string `json:"prompt"`
 }
 
-// ollamaEmbedResponse Ollama嵌入响应格式
+// ollamaEmbedResponse Ollama嵌入响应格式（旧版 /api/embeddings）
 type ollamaEmbedResponse struct {
 	Embedding []float64 `json:"embedding"`
 }
 
+// ollamaBatchRequest Ollama批量嵌入请求格式（/api/embed）
+type ollamaBatchRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// ollamaBatchResponse Ollama批量嵌入响应格式（/api/embed）
+type ollamaBatchResponse struct {
+	Model      string      `json:"model"`
+	Embeddings [][]float64 `json:"embeddings"`
+}
+
 // NewOllamaEmbedder 创建新的Ollama嵌入服务
+// 默认会检查连接并检测维度；设置 Options["lazy"] 时不访问网络，由首次调用或 Init 完成
 func NewOllamaEmbedder(config Config) (*OllamaEmbedder, error) {
-	logger := NewLogger("ollama-embedder")
+	return NewOllamaEmbedderContext(context.Background(), config)
+}
+
+// NewOllamaEmbedderContext 创建新的Ollama嵌入服务，ctx 控制初始化时的网络请求
+func NewOllamaEmbedderContext(ctx context.Context, config Config) (*OllamaEmbedder, error) {
+	logger := NewSlogLogger(config.Logger, "ollama-embedder")
 
 	embedder := &OllamaEmbedder{
 		baseURL: strings.TrimSuffix(config.BaseURL, "/"),
@@ -40,52 +83,106 @@ func NewOllamaEmbedder(config Config) (*OllamaEmbedder, error) {
 		httpClient: &http.Client{
 			Timeout: config.Timeout,
 		},
-		logger: logger,
+		concurrency: optionInt(config.Options, OptionConcurrency, 1),
+		retry:       withRetryLogging(config.Retry, logger),
+		logger:      logger,
+		tracer:      config.Tracer,
+	}
+	if embedder.concurrency > 0 {
+		embedder.slots = make(chan struct{}, embedder.concurrency)
+	}
+	// 维度优先取选项，其次取模型注册表，都未知时由 Init 或首次嵌入确定
+	dimension := optionInt(config.Options, OptionDimension, 0)
+	if dimension == 0 {
+		dimension = modelDimension(config.Model)
 	}
+	embedder.dimension.Store(int64(dimension))
 
-	// 测试连接并获取模型信息
-	ctx := context.Background()
-	if err := embedder.Health(ctx); err != nil {
-		return nil, fmt.Errorf("failed to connect to Ollama: %w", err)
+	if optionBool(config.Options, OptionLazy, false) {
+		logger.InfoContext(ctx, "Ollama嵌入服务已创建（延迟初始化）",
+			String("base_url", config.BaseURL),
+			String("model", config.Model))
+		return embedder, nil
 	}
 
-	// 获取嵌入维度
-	if err := embedder.detectDimension(ctx); err != nil {
-		return nil, fmt.Errorf("failed to detect embedding dimension: %w", err)
+	if err := embedder.Init(ctx); err != nil {
+		return nil, err
 	}
 
-	logger.Info("Ollama嵌入服务初始化成功",
+	logger.InfoContext(ctx, "Ollama嵌入服务初始化成功",
 		String("base_url", config.BaseURL),
 		String("model", config.Model),
-		Int("dimension", embedder.dimension))
+		Int("dimension", embedder.GetDimension()))
 
 	return embedder, nil
 }
 
+// Init 检查连接并检测嵌入维度，已知维度时跳过检测
+// 延迟初始化时可在服务启动后显式调用，失败后可以再次调用
+func (e *OllamaEmbedder) Init(ctx context.Context) error {
+	if err := e.Health(ctx); err != nil {
+		return fmt.Errorf("failed to connect to Ollama: %w", err)
+	}
+
+	if e.GetDimension() > 0 {
+		return nil
+	}
+	if err := e.detectDimension(ctx); err != nil {
+		return fmt.Errorf("failed to detect embedding dimension: %w", err)
+	}
+	return nil
+}
+
 // Embed 批量嵌入多个文本
+// 优先使用 /api/embed 一次请求完成整批嵌入，服务端版本过旧时回退到逐个请求
 func (e *OllamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
 	if len(texts) == 0 {
 		return [][]float32{}, nil
 	}
 
-	e.logger.Debug("开始嵌入文本", Int("count", len(texts)))
+	e.logger.DebugContext(ctx, "开始嵌入文本", Int("count", len(texts)))
+
+	if !e.legacy.Load() {
+		embeddings, err := e.embedBatch(ctx, texts)
+		if err == nil {
+			if err := e.recordDimension(ctx, embeddings); err != nil {
+				return nil, err
+			}
+			e.logger.DebugContext(ctx, "文本嵌入完成", Int("count", len(embeddings)))
+			return embeddings, nil
+		}
+		if !isEndpointNotFound(err) {
+			e.logger.ErrorContext(ctx, "批量嵌入文本失败", Error(err), Int("count", len(texts)))
+			return nil, fmt.Errorf("failed to embed batch of %d texts: %w", len(texts), err)
+		}
+		e.logger.WarnContext(ctx, "服务端不支持 /api/embed，回退到 /api/embeddings")
+		e.legacy.Store(true)
+	}
 
-	var allEmbeddings [][]float32
+	allEmbeddings := make([][]float32, len(texts))
 
-	// Ollama通常只支持单个文本嵌入，需要逐个处理
-	for i, text := range texts {
-		embedding, err := e.embedSingle(ctx, text)
+	// 旧版接口只支持单个文本嵌入，需要逐个处理，按 concurrency 并发
+	err := runParallel(ctx, len(texts), e.concurrency, func(ctx context.Context, i int) error {
+		embedding, err := e.embedLegacy(ctx, texts[i])
 		if err != nil {
-			e.logger.Error("嵌入文本失败",
+			err = withIndex(err, i)
+			e.logger.ErrorContext(ctx, "嵌入文本失败",
 				Error(err),
 				Int("index", i),
-				String("text_preview", e.getTextPreview(text)))
-			return nil, fmt.Errorf("failed to embed text at index %d: %w", i, err)
+				String("text_preview", e.getTextPreview(texts[i])))
+			return fmt.Errorf("failed to embed text at index %d: %w", i, err)
 		}
-		allEmbeddings = append(allEmbeddings, embedding)
+		allEmbeddings[i] = embedding
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	e.logger.Debug("文本嵌入完成", Int("count", len(allEmbeddings)))
+	if err := e.recordDimension(ctx, allEmbeddings); err != nil {
+		return nil, err
+	}
+	e.logger.DebugContext(ctx, "文本嵌入完成", Int("count", len(allEmbeddings)))
 	return allEmbeddings, nil
 }
 
@@ -94,34 +191,43 @@ func (e *OllamaEmbedder) EmbedSingle(ctx context.Context, text string) ([]float3
 	return e.embedSingle(ctx, text)
 }
 
-// BatchEmbed 分批处理大量文本
+// BatchEmbed 分批处理大量文本，批次之间按 concurrency 并发
+// 同时进行的 HTTP 请求总数不超过 concurrency（包括旧版接口逐个请求时）
 func (e *OllamaEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
 	if batchSize <= 0 {
 		batchSize = len(texts)
 	}
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
 
-	var allEmbeddings [][]float32
-	for i := 0; i < len(texts); i += batchSize {
-		end := i + batchSize
+	allEmbeddings := make([][]float32, len(texts))
+	batches := (len(texts) + batchSize - 1) / batchSize
+	err := runParallel(ctx, batches, e.concurrency, func(ctx context.Context, b int) error {
+		start := b * batchSize
+		end := start + batchSize
 		if end > len(texts) {
 			end = len(texts)
 		}
 
-		batch := texts[i:end]
-		embeddings, err := e.Embed(ctx, batch)
+		embeddings, err := e.Embed(ctx, texts[start:end])
 		if err != nil {
-			return nil, err
+			return err
 		}
 
-		allEmbeddings = append(allEmbeddings, embeddings...)
+		copy(allEmbeddings[start:end], embeddings)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return allEmbeddings, nil
 }
 
-// GetDimension 获取嵌入维度
+// GetDimension 获取嵌入维度，延迟初始化且尚未嵌入时返回 0
 func (e *OllamaEmbedder) GetDimension() int {
-	return e.dimension
+	return int(e.dimension.Load())
 }
 
 // GetModel 获取模型名称
@@ -130,7 +236,7 @@ func (e *OllamaEmbedder) GetModel() string {
 }
 
 // Health 健康检查
-func (e *OllamaEmbedder) Health(ctx context.Context) error {
+func (e *OllamaEmbedder) Health(ctx context.Context) (err error) {
 	url := fmt.Sprintf("%s/api/version", e.baseURL)
 
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
@@ -138,14 +244,16 @@ func (e *OllamaEmbedder) Health(ctx context.Context) error {
 		return err
 	}
 
+	req, finish := traceHTTP(e.tracer, "ollama", e.model, req)
 	resp, err := e.httpClient.Do(req)
+	defer func() { finish(resp, err) }()
 	if err != nil {
-		return err
+		return newTransportError(ctx, "ollama", e.model, err)
 	}
 	defer e.closeResponse(resp)
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("ollama health check failed: HTTP %d", resp.StatusCode)
+		return newStatusError("ollama", e.model, resp)
 	}
 
 	return nil
@@ -153,6 +261,41 @@ func (e *OllamaEmbedder) Health(ctx context.Context) error {
 
 // embedSingle 嵌入单个文本（私有方法）
 func (e *OllamaEmbedder) embedSingle(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := e.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// embedBatch 通过 /api/embed 一次请求嵌入多个文本（私有方法）
+func (e *OllamaEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	url := fmt.Sprintf("%s/api/embed", e.baseURL)
+
+	reqData := ollamaBatchRequest{
+		Model: e.model,
+		Input: texts,
+	}
+
+	var respData ollamaBatchResponse
+	if err := e.makeRequest(ctx, url, reqData, &respData); err != nil {
+		return nil, err
+	}
+
+	if len(respData.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("%w: expected %d embeddings, got %d", ErrInvalidResponse, len(texts), len(respData.Embeddings))
+	}
+
+	result := make([][]float32, len(respData.Embeddings))
+	for i, embedding := range respData.Embeddings {
+		result[i] = toFloat32(embedding)
+	}
+
+	return result, nil
+}
+
+// embedLegacy 通过旧版 /api/embeddings 嵌入单个文本（私有方法）
+func (e *OllamaEmbedder) embedLegacy(ctx context.Context, text string) ([]float32, error) {
 	url := fmt.Sprintf("%s/api/embeddings", e.baseURL)
 
 	reqData := ollamaEmbedRequest{
@@ -165,30 +308,63 @@ func (e *OllamaEmbedder) embedSingle(ctx context.Context, text string) ([]float3
 		return nil, fmt.Errorf("failed to embed text: %w", err)
 	}
 
-	// 转换 []float64 到 []float32
-	result := make([]float32, len(respData.Embedding))
-	for i, val := range respData.Embedding {
-		result[i] = float32(val)
-	}
-
-	return result, nil
+	return toFloat32(respData.Embedding), nil
 }
 
 // detectDimension 检测嵌入维度（私有方法）
 func (e *OllamaEmbedder) detectDimension(ctx context.Context) error {
-	// 使用测试文本获取嵌入维度
-	embedding, err := e.embedSingle(ctx, "test")
-	if err != nil {
+	// 使用测试文本获取嵌入维度，Embed 会记录结果
+	if _, err := e.embedSingle(ctx, "test"); err != nil {
 		return err
 	}
 
-	e.dimension = len(embedding)
-	e.logger.Debug("检测到嵌入维度", Int("dimension", e.dimension))
+	e.logger.DebugContext(ctx, "检测到嵌入维度", Int("dimension", e.GetDimension()))
 	return nil
 }
 
-// makeRequest 发送请求到Ollama（私有方法）
+// recordDimension 核对嵌入结果的维度（私有方法）
+// 首次响应确定实际维度，与选项或注册表不一致时按响应更正并记录警告；之后维度变化返回 ErrInvalidResponse
+func (e *OllamaEmbedder) recordDimension(ctx context.Context, embeddings [][]float32) error {
+	if len(embeddings) == 0 {
+		return nil
+	}
+	actual := len(embeddings[0])
+	for i, embedding := range embeddings {
+		if len(embedding) != actual {
+			return fmt.Errorf("%w: embedding %d has dimension %d, expected %d", ErrInvalidResponse, i, len(embedding), actual)
+		}
+	}
+
+	if !e.verified.Load() {
+		e.dimensionMu.Lock()
+		defer e.dimensionMu.Unlock()
+		if !e.verified.Load() {
+			if configured := e.GetDimension(); configured > 0 && configured != actual {
+				e.logger.WarnContext(ctx, "实际嵌入维度与配置不一致，已按响应更正",
+					Int("configured", configured),
+					Int("dimension", actual))
+			}
+			e.dimension.Store(int64(actual))
+			e.verified.Store(true)
+			return nil
+		}
+	}
+
+	if expected := e.GetDimension(); actual != expected {
+		return fmt.Errorf("%w: got dimension %d, expected %d", ErrInvalidResponse, actual, expected)
+	}
+	return nil
+}
+
+// makeRequest 发送请求到Ollama，按重试策略处理瞬时错误（私有方法）
 func (e *OllamaEmbedder) makeRequest(ctx context.Context, url string, reqData interface{}, respData interface{}) error {
+	return e.retry.Do(ctx, func(ctx context.Context) error {
+		return e.doRequest(ctx, url, reqData, respData)
+	})
+}
+
+// doRequest 发送单次请求到Ollama（私有方法）
+func (e *OllamaEmbedder) doRequest(ctx context.Context, url string, reqData interface{}, respData interface{}) (err error) {
 	jsonData, err := json.Marshal(reqData)
 	if err != nil {
 		return err
@@ -201,22 +377,35 @@ func (e *OllamaEmbedder) makeRequest(ctx context.Context, url string, reqData in
 
 	req.Header.Set("Content-Type", "application/json")
 
+	if e.slots != nil {
+		select {
+		case e.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		defer func() { <-e.slots }()
+	}
+
+	req, finish := traceHTTP(e.tracer, "ollama", e.model, req)
 	resp, err := e.httpClient.Do(req)
+	defer func() { finish(resp, err) }()
 	if err != nil {
-		return err
+		return newTransportError(ctx, "ollama", e.model, err)
 	}
 	defer e.closeResponse(resp)
 
 	if resp.StatusCode >= 400 {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(bodyBytes))
+		return newStatusError("ollama", e.model, resp)
 	}
 
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return err
+		return newTransportError(ctx, "ollama", e.model, err)
 	}
-	return json.Unmarshal(bodyBytes, respData)
+	if err := json.Unmarshal(bodyBytes, respData); err != nil {
+		return newDecodeError(err)
+	}
+	return nil
 }
 
 // closeResponse 安全关闭响应体（私有方法）
@@ -232,4 +421,26 @@ func (e *OllamaEmbedder) getTextPreview(text string) string {
 		return text
 	}
 	return text[:47] + "..."
-}
\ No newline at end of file
+}
+
+// isEndpointNotFound 判断错误是否表示服务端缺少该接口（而非模型不存在等业务错误）
+func isEndpointNotFound(err error) bool {
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) {
+		return false
+	}
+	if providerErr.StatusCode != http.StatusNotFound && providerErr.StatusCode != http.StatusMethodNotAllowed {
+		return false
+	}
+	// Ollama 的业务错误以 {"error": "..."} 返回，路由缺失时则是纯文本
+	return !strings.Contains(providerErr.Message, `"error"`)
+}
+
+// toFloat32 转换 []float64 到 []float32
+func toFloat32(values []float64) []float32 {
+	result := make([]float32, len(values))
+	for i, val := range values {
+		result[i] = float32(val)
+	}
+	return result
+}
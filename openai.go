@@ -0,0 +1,337 @@
+package embedder
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// OpenAI 兼容服务的选项键
+const (
+	// OptionAPIKey API密钥
+	OptionAPIKey = "api_key"
+	// OptionAPIKeyEnv 读取API密钥的环境变量名
+	// 未设置时只有 base_url 指向 api.openai.com 才读取 OPENAI_API_KEY，避免把密钥发送给其他服务
+	OptionAPIKeyEnv = "api_key_env"
+	// OptionDimensions 请求服务端输出的向量维度（text-embedding-3 等模型支持）
+	OptionDimensions = "dimensions"
+	// OptionEncodingFormat 响应编码格式，float 或 base64
+	OptionEncodingFormat = "encoding_format"
+)
+
+// defaultAPIKeyEnv 默认的API密钥环境变量
+const defaultAPIKeyEnv = "OPENAI_API_KEY"
+
+// OpenAIEmbedder OpenAI兼容协议（POST /v1/embeddings）的嵌入服务实现
+// 适用于 OpenAI、vLLM、LocalAI、llama.cpp server 等
+type OpenAIEmbedder struct {
+	baseURL        string
+	model          string
+	apiKey         string
+	dimensions     int
+	encodingFormat string
+	httpClient     *http.Client
+	dimension      int
+	retry          RetryPolicy
+	logger         *Logger
+	tracer         Tracer
+}
+
+// openAIEmbedRequest OpenAI嵌入请求格式
+type openAIEmbedRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	Dimensions     int      `json:"dimensions,omitempty"`
+	EncodingFormat string   `json:"encoding_format,omitempty"`
+}
+
+// openAIEmbedResponse OpenAI嵌入响应格式
+type openAIEmbedResponse struct {
+	Data []struct {
+		Index     int             `json:"index"`
+		Embedding json.RawMessage `json:"embedding"`
+	} `json:"data"`
+	Model string `json:"model"`
+	Usage struct {
+		PromptTokens int `json:"prompt_tokens"`
+		TotalTokens  int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// NewOpenAIEmbedder 创建新的OpenAI兼容嵌入服务
+// BaseURL 可以是服务根地址或以 /v1 结尾的API地址
+func NewOpenAIEmbedder(config Config) (*OpenAIEmbedder, error) {
+	return NewOpenAIEmbedderContext(context.Background(), config)
+}
+
+// NewOpenAIEmbedderContext 创建新的OpenAI兼容嵌入服务，ctx 控制维度检测请求
+func NewOpenAIEmbedderContext(ctx context.Context, config Config) (*OpenAIEmbedder, error) {
+	logger := NewSlogLogger(config.Logger, "openai-embedder")
+
+	apiKey := optionString(config.Options, OptionAPIKey, "")
+	if apiKey == "" {
+		env := optionString(config.Options, OptionAPIKeyEnv, "")
+		if env == "" && isOpenAIHost(config.BaseURL) {
+			env = defaultAPIKeyEnv
+		}
+		if env != "" {
+			apiKey = os.Getenv(env)
+		}
+	}
+
+	encodingFormat := optionString(config.Options, OptionEncodingFormat, "float")
+	if encodingFormat != "float" && encodingFormat != "base64" {
+		return nil, fmt.Errorf("%w: unsupported encoding_format: %s", ErrInvalidConfig, encodingFormat)
+	}
+
+	baseURL := strings.TrimSuffix(config.BaseURL, "/")
+	if !strings.HasSuffix(baseURL, "/v1") {
+		baseURL += "/v1"
+	}
+
+	embedder := &OpenAIEmbedder{
+		baseURL:        baseURL,
+		model:          config.Model,
+		apiKey:         apiKey,
+		dimensions:     optionInt(config.Options, OptionDimensions, 0),
+		encodingFormat: encodingFormat,
+		httpClient: &http.Client{
+			Timeout: config.Timeout,
+		},
+		retry:  withRetryLogging(config.Retry, logger),
+		logger: logger,
+		tracer: config.Tracer,
+	}
+
+	// 已知维度（请求指定或模型注册表中存在）时只检查连接，否则探测维度
+	embedder.dimension = embedder.dimensions
+	if embedder.dimension == 0 {
+		embedder.dimension = modelDimension(config.Model)
+	}
+	if embedder.dimension > 0 {
+		if err := embedder.Health(ctx); err != nil {
+			return nil, err
+		}
+	} else if err := embedder.detectDimension(ctx); err != nil {
+		return nil, fmt.Errorf("failed to detect embedding dimension: %w", err)
+	}
+
+	logger.InfoContext(ctx, "OpenAI兼容嵌入服务初始化成功",
+		String("base_url", baseURL),
+		String("model", config.Model),
+		Int("dimension", embedder.dimension))
+
+	return embedder, nil
+}
+
+// Embed 批量嵌入多个文本，一次请求发送全部输入
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return [][]float32{}, nil
+	}
+
+	e.logger.DebugContext(ctx, "开始嵌入文本", Int("count", len(texts)))
+
+	reqData := openAIEmbedRequest{
+		Model:          e.model,
+		Input:          texts,
+		Dimensions:     e.dimensions,
+		EncodingFormat: e.encodingFormat,
+	}
+
+	var respData openAIEmbedResponse
+	err := e.retry.Do(ctx, func(ctx context.Context) error {
+		return e.makeRequest(ctx, "POST", e.baseURL+"/embeddings", reqData, &respData)
+	})
+	if err != nil {
+		e.logger.ErrorContext(ctx, "批量嵌入文本失败", Error(err), Int("count", len(texts)))
+		return nil, fmt.Errorf("failed to embed batch of %d texts: %w", len(texts), err)
+	}
+
+	if len(respData.Data) != len(texts) {
+		return nil, fmt.Errorf("%w: expected %d embeddings, got %d", ErrInvalidResponse, len(texts), len(respData.Data))
+	}
+
+	// 服务端不保证按输入顺序返回，按 index 排序
+	sort.Slice(respData.Data, func(i, j int) bool {
+		return respData.Data[i].Index < respData.Data[j].Index
+	})
+
+	result := make([][]float32, len(respData.Data))
+	for i, item := range respData.Data {
+		embedding, err := e.decodeEmbedding(item.Embedding)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode embedding at index %d: %w", item.Index, newDecodeError(err))
+		}
+		result[i] = embedding
+	}
+
+	e.logger.DebugContext(ctx, "文本嵌入完成",
+		Int("count", len(result)),
+		Int("total_tokens", respData.Usage.TotalTokens))
+	return result, nil
+}
+
+// EmbedSingle 嵌入单个文本
+func (e *OpenAIEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := e.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// BatchEmbed 分批处理大量文本，每批一次请求
+func (e *OpenAIEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	if batchSize <= 0 {
+		batchSize = len(texts)
+	}
+
+	var allEmbeddings [][]float32
+	for i := 0; i < len(texts); i += batchSize {
+		end := i + batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		embeddings, err := e.Embed(ctx, texts[i:end])
+		if err != nil {
+			return nil, err
+		}
+
+		allEmbeddings = append(allEmbeddings, embeddings...)
+	}
+
+	return allEmbeddings, nil
+}
+
+// GetDimension 获取嵌入维度
+func (e *OpenAIEmbedder) GetDimension() int {
+	return e.dimension
+}
+
+// GetModel 获取模型名称
+func (e *OpenAIEmbedder) GetModel() string {
+	return e.model
+}
+
+// Health 健康检查，请求 /v1/models 确认服务可用且密钥有效
+func (e *OpenAIEmbedder) Health(ctx context.Context) error {
+	if err := e.makeRequest(ctx, "GET", e.baseURL+"/models", nil, nil); err != nil {
+		return fmt.Errorf("openai health check failed: %w", err)
+	}
+	return nil
+}
+
+// detectDimension 检测嵌入维度（私有方法）
+func (e *OpenAIEmbedder) detectDimension(ctx context.Context) error {
+	embedding, err := e.EmbedSingle(ctx, "test")
+	if err != nil {
+		return err
+	}
+
+	e.dimension = len(embedding)
+	e.logger.DebugContext(ctx, "检测到嵌入维度", Int("dimension", e.dimension))
+	return nil
+}
+
+// decodeEmbedding 按编码格式解析单个向量（私有方法）
+func (e *OpenAIEmbedder) decodeEmbedding(raw json.RawMessage) ([]float32, error) {
+	// 部分兼容服务会忽略 encoding_format，因此按实际JSON类型解析
+	if len(raw) > 0 && raw[0] == '"' {
+		var encoded string
+		if err := json.Unmarshal(raw, &encoded); err != nil {
+			return nil, err
+		}
+		return decodeBase64Embedding(encoded)
+	}
+
+	var values []float64
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, err
+	}
+	return toFloat32(values), nil
+}
+
+// makeRequest 发送请求到OpenAI兼容服务（私有方法）
+func (e *OpenAIEmbedder) makeRequest(ctx context.Context, method, url string, reqData interface{}, respData interface{}) (err error) {
+	var body io.Reader
+	if reqData != nil {
+		jsonData, err := json.Marshal(reqData)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(jsonData)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return err
+	}
+
+	if reqData != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.apiKey)
+	}
+
+	req, finish := traceHTTP(e.tracer, "openai", e.model, req)
+	resp, err := e.httpClient.Do(req)
+	defer func() { finish(resp, err) }()
+	if err != nil {
+		return newTransportError(ctx, "openai", e.model, err)
+	}
+	defer e.closeResponse(resp)
+
+	if resp.StatusCode >= 400 {
+		return newStatusError("openai", e.model, resp)
+	}
+
+	if respData == nil {
+		return nil
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return newTransportError(ctx, "openai", e.model, err)
+	}
+	if err := json.Unmarshal(bodyBytes, respData); err != nil {
+		return newDecodeError(err)
+	}
+	return nil
+}
+
+// closeResponse 安全关闭响应体（私有方法）
+func (e *OpenAIEmbedder) closeResponse(resp *http.Response) {
+	if err := resp.Body.Close(); err != nil {
+		e.logger.Warn("关闭响应体失败", Error(err))
+	}
+}
+
+// decodeBase64Embedding 解析base64编码的小端float32数组
+func decodeBase64Embedding(encoded string) ([]float32, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("invalid base64 embedding length: %d bytes", len(data))
+	}
+
+	return decodeFloat32s(data), nil
+}
+
+// isOpenAIHost 判断 baseURL 是否为 OpenAI 官方 API
+func isOpenAIHost(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	return err == nil && strings.EqualFold(u.Hostname(), "api.openai.com")
+}
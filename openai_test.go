@@ -0,0 +1,150 @@
+package embedder
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newOpenAIMockServer 创建模拟的OpenAI兼容服务，向量首元素为输入长度
+func newOpenAIMockServer(t *testing.T, apiKey string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+apiKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": {"message": "invalid api key"}}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/models":
+			w.Write([]byte(`{"data": []}`))
+		case "/v1/embeddings":
+			var req openAIEmbedRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			dimension := 4
+			if req.Dimensions > 0 {
+				dimension = req.Dimensions
+			}
+
+			type item struct {
+				Index     int         `json:"index"`
+				Embedding interface{} `json:"embedding"`
+			}
+			var data []item
+			// 逆序返回，验证客户端按 index 重排
+			for i := len(req.Input) - 1; i >= 0; i-- {
+				vector := make([]float32, dimension)
+				vector[0] = float32(len(req.Input[i]))
+				if req.EncodingFormat == "base64" {
+					buf := make([]byte, 4*dimension)
+					for j, v := range vector {
+						binary.LittleEndian.PutUint32(buf[j*4:], math.Float32bits(v))
+					}
+					data = append(data, item{Index: i, Embedding: base64.StdEncoding.EncodeToString(buf)})
+				} else {
+					data = append(data, item{Index: i, Embedding: vector})
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": data, "model": req.Model})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestOpenAIEmbedderWithMockServer(t *testing.T) {
+	server := newOpenAIMockServer(t, "secret")
+	defer server.Close()
+
+	t.Setenv("TEST_OPENAI_KEY", "secret")
+	config := Config{
+		Provider: "openai",
+		BaseURL:  server.URL,
+		Model:    "text-embedding-3-small",
+		Timeout:  5 * time.Second,
+		Options: map[string]interface{}{
+			OptionAPIKeyEnv:      "TEST_OPENAI_KEY",
+			OptionDimensions:     8,
+			OptionEncodingFormat: "base64",
+		},
+	}
+
+	embedder, err := NewOpenAIEmbedder(config)
+	if err != nil {
+		t.Fatalf("Failed to create OpenAIEmbedder: %v", err)
+	}
+	if embedder.GetDimension() != 8 {
+		t.Errorf("Expected dimension 8, got %d", embedder.GetDimension())
+	}
+
+	ctx := context.Background()
+	if err := embedder.Health(ctx); err != nil {
+		t.Errorf("Health check failed: %v", err)
+	}
+
+	texts := []string{"a", "bb", "ccc"}
+	embeddings, err := embedder.BatchEmbed(ctx, texts, 2)
+	if err != nil {
+		t.Fatalf("BatchEmbed failed: %v", err)
+	}
+	if len(embeddings) != len(texts) {
+		t.Fatalf("Expected %d embeddings, got %d", len(texts), len(embeddings))
+	}
+	for i, embedding := range embeddings {
+		if int(embedding[0]) != len(texts[i]) {
+			t.Errorf("Embedding %d out of order: got %v", i, embedding)
+		}
+	}
+}
+
+func TestOpenAIEmbedderUnauthorized(t *testing.T) {
+	server := newOpenAIMockServer(t, "secret")
+	defer server.Close()
+
+	_, err := NewOpenAIEmbedder(Config{
+		BaseURL: server.URL + "/v1",
+		Model:   "text-embedding-3-small",
+		Timeout: 5 * time.Second,
+		Options: map[string]interface{}{OptionAPIKey: "wrong"},
+	})
+	if err == nil {
+		t.Fatal("Expected error for invalid api key")
+	}
+}
+
+func TestOpenAIEmbedderAPIKeyEnvScope(t *testing.T) {
+	t.Setenv(defaultAPIKeyEnv, "hosted-key")
+	server := newOpenAIMockServer(t, "hosted-key")
+	defer server.Close()
+
+	// 其他服务不会收到 OPENAI_API_KEY
+	_, err := NewOpenAIEmbedder(Config{BaseURL: server.URL, Model: "text-embedding-3-small", Timeout: 5 * time.Second})
+	if !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("Expected key not to be sent to a non-OpenAI host, got %v", err)
+	}
+
+	// 显式指定 api_key_env 时读取
+	_, err = NewOpenAIEmbedder(Config{
+		BaseURL: server.URL,
+		Model:   "text-embedding-3-small",
+		Timeout: 5 * time.Second,
+		Options: map[string]interface{}{OptionAPIKeyEnv: defaultAPIKeyEnv},
+	})
+	if err != nil {
+		t.Fatalf("Expected explicit api_key_env to be used, got %v", err)
+	}
+
+	if !isOpenAIHost("https://api.openai.com/v1") || isOpenAIHost("http://localhost:8000/v1") {
+		t.Error("Unexpected isOpenAIHost result")
+	}
+}
@@ -0,0 +1,21 @@
+module github.com/Kizunad/modular-embedder/otelembedder
+
+go 1.21
+
+require (
+	github.com/Kizunad/modular-embedder v0.0.0
+	go.opentelemetry.io/otel v1.24.0
+	go.opentelemetry.io/otel/sdk v1.24.0
+	go.opentelemetry.io/otel/trace v1.24.0
+)
+
+require (
+	github.com/go-logr/logr v1.4.1 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	go.opentelemetry.io/otel/metric v1.24.0 // indirect
+	golang.org/x/sys v0.18.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0 // indirect
+)
+
+// 根模块尚未发布版本，依赖仓库内的本地目录；根模块打出标签后改为 require 该版本并删除此行
+replace github.com/Kizunad/modular-embedder => ../
@@ -0,0 +1,121 @@
+// Package otelembedder 将 embedder.Tracer 适配到 OpenTelemetry 追踪 API
+//
+//	e, err := embedder.New("ollama").
+//		WithTracer(otelembedder.NewTracer()).
+//		Build()
+//
+// 独立为子模块，只有使用它的程序才会依赖 OpenTelemetry
+package otelembedder
+
+import (
+	"context"
+
+	embedder "github.com/Kizunad/modular-embedder"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName 默认的 instrumentation scope 名称
+const instrumentationName = "github.com/Kizunad/modular-embedder"
+
+// Tracer 基于 OpenTelemetry 的 embedder.Tracer 实现
+type Tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// Option Tracer 的可选配置
+type Option func(*options)
+
+// options NewTracer 的配置
+type options struct {
+	provider   trace.TracerProvider
+	propagator propagation.TextMapPropagator
+}
+
+// WithTracerProvider 指定 TracerProvider，默认使用全局 otel.GetTracerProvider()
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(o *options) {
+		o.provider = provider
+	}
+}
+
+// WithPropagator 指定向服务端传播上下文的 propagator，默认使用全局 otel.GetTextMapPropagator()
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return func(o *options) {
+		o.propagator = propagator
+	}
+}
+
+// NewTracer 创建 OpenTelemetry 追踪钩子
+func NewTracer(opts ...Option) *Tracer {
+	o := options{
+		provider:   otel.GetTracerProvider(),
+		propagator: otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Tracer{
+		tracer:     o.provider.Tracer(instrumentationName),
+		propagator: o.propagator,
+	}
+}
+
+// Begin 开始 span，HTTP 请求的 span 为 Client 类型并向请求头注入追踪上下文
+func (t *Tracer) Begin(ctx context.Context, span embedder.SpanStart) context.Context {
+	attrs := []attribute.KeyValue{
+		attribute.String(embedder.AttrProvider, span.Provider),
+		attribute.String(embedder.AttrModel, span.Model),
+	}
+
+	kind := trace.SpanKindInternal
+	if span.Name == embedder.SpanHTTP {
+		kind = trace.SpanKindClient
+		attrs = append(attrs,
+			attribute.String("http.request.method", span.Method),
+			attribute.String("url.full", span.URL),
+			attribute.Int(embedder.AttrAttempt, span.Attempt))
+	} else if span.Name != embedder.SpanHealth {
+		attrs = append(attrs,
+			attribute.Int(embedder.AttrBatchSize, span.BatchSize),
+			attribute.Int(embedder.AttrTextCount, span.TextCount))
+	}
+
+	ctx, _ = t.tracer.Start(ctx, span.Name, trace.WithSpanKind(kind), trace.WithAttributes(attrs...))
+	if span.Header != nil {
+		t.propagator.Inject(ctx, propagation.HeaderCarrier(span.Header))
+	}
+	return ctx
+}
+
+// End 记录结果并结束 span，失败或 HTTP 状态码 >= 400 时标记为错误
+func (t *Tracer) End(ctx context.Context, result embedder.SpanEnd) {
+	span := trace.SpanFromContext(ctx)
+
+	if result.StatusCode > 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", result.StatusCode))
+	}
+	if result.Dimension > 0 {
+		span.SetAttributes(attribute.Int(embedder.AttrDimension, result.Dimension))
+	}
+	if result.Retries > 0 {
+		span.SetAttributes(attribute.Int(embedder.AttrRetries, result.Retries))
+	}
+
+	if result.Err != nil {
+		span.RecordError(result.Err)
+		span.SetStatus(codes.Error, result.Err.Error())
+		if kind := embedder.ErrorKind(result.Err); kind != "" {
+			span.SetAttributes(attribute.String("error.type", kind))
+		}
+	} else if result.StatusCode >= 400 {
+		span.SetStatus(codes.Error, "")
+	}
+
+	span.End()
+}
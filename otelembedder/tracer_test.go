@@ -0,0 +1,107 @@
+package otelembedder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	embedder "github.com/Kizunad/modular-embedder"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// attrs 将 span 属性转换为 map 便于断言
+func attrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	result := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		result[kv.Key] = kv.Value
+	}
+	return result
+}
+
+func TestTracerWithOllama(t *testing.T) {
+	var calls int
+	var traceparents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": [][]float64{{1, 2, 3}, {4, 5, 6}}})
+	}))
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := NewTracer(WithTracerProvider(provider), WithPropagator(propagation.TraceContext{}))
+
+	e, err := embedder.New("ollama").
+		WithBaseURL(server.URL).
+		WithModel("otel-test").
+		WithRetry(embedder.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}).
+		WithOption(embedder.OptionLazy, true).
+		WithTracer(tracer).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if _, err := e.Embed(context.Background(), []string{"a", "b"}); err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+
+	// 子 span 先结束
+	first, second, root := spans[0], spans[1], spans[2]
+	if root.Name() != embedder.SpanEmbed || root.SpanKind() != trace.SpanKindInternal {
+		t.Errorf("Unexpected root span %s (%v)", root.Name(), root.SpanKind())
+	}
+	rootAttrs := attrs(root)
+	for key, want := range map[string]int64{
+		embedder.AttrBatchSize: 2,
+		embedder.AttrTextCount: 2,
+		embedder.AttrDimension: 3,
+		embedder.AttrRetries:   1,
+	} {
+		if got := rootAttrs[attribute.Key(key)].AsInt64(); got != want {
+			t.Errorf("%s: expected %d, got %d", key, want, got)
+		}
+	}
+	if rootAttrs[embedder.AttrProvider].AsString() != "ollama" || rootAttrs[embedder.AttrModel].AsString() != "otel-test" {
+		t.Errorf("Unexpected root attributes: %v", rootAttrs)
+	}
+
+	for i, span := range []sdktrace.ReadOnlySpan{first, second} {
+		if span.Name() != embedder.SpanHTTP || span.SpanKind() != trace.SpanKindClient {
+			t.Errorf("Unexpected HTTP span %s (%v)", span.Name(), span.SpanKind())
+		}
+		if span.Parent().SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("HTTP span %d is not a child of the embed span", i)
+		}
+		if got := attrs(span)[embedder.AttrAttempt].AsInt64(); got != int64(i+1) {
+			t.Errorf("Expected attempt %d, got %d", i+1, got)
+		}
+		if traceparents[i] == "" {
+			t.Errorf("Expected traceparent header on attempt %d", i+1)
+		}
+	}
+	if first.Status().Code != codes.Error || attrs(first)["http.response.status_code"].AsInt64() != 503 {
+		t.Errorf("Expected failed first attempt, got %v %v", first.Status(), attrs(first))
+	}
+	if second.Status().Code == codes.Error {
+		t.Errorf("Expected successful second attempt, got %v", second.Status())
+	}
+}
@@ -0,0 +1,336 @@
+package embedder
+
+import (
+	"context"
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// OverflowMode 超长输入的处理方式
+type OverflowMode string
+
+const (
+	// OverflowError 返回 ErrInputTooLong（默认）
+	OverflowError OverflowMode = "error"
+	// OverflowTruncateHead 截掉开头，保留结尾部分
+	OverflowTruncateHead OverflowMode = "truncate_head"
+	// OverflowTruncateTail 截掉结尾，保留开头部分
+	OverflowTruncateTail OverflowMode = "truncate_tail"
+	// OverflowChunkAndPool 切分为多段分别嵌入，再池化为一个向量
+	OverflowChunkAndPool OverflowMode = "chunk_and_pool"
+)
+
+// PoolingMode chunk_and_pool 的池化方式
+type PoolingMode string
+
+const (
+	// PoolingMean 各段向量取平均（默认）
+	PoolingMean PoolingMode = "mean"
+	// PoolingWeighted 按各段长度加权平均
+	PoolingWeighted PoolingMode = "weighted"
+)
+
+// OverflowPolicy 超长输入策略
+// MaxChars 和 MaxTokens 都为 0 时不做检查；同时设置时任一超出即视为超长
+// 通过工厂创建时，设置了 Mode 但未设置限制会使用模型注册表中的 MaxTokens
+type OverflowPolicy struct {
+	// Mode 处理方式，为空时等同 error
+	Mode OverflowMode `yaml:"mode"`
+	// MaxChars 最大字符数（按 Unicode 字符计）
+	MaxChars int `yaml:"max_chars"`
+	// MaxTokens 最大近似 token 数：ASCII 约 4 字符 1 token，其他字符每个 1 token
+	MaxTokens int `yaml:"max_tokens"`
+	// Pooling chunk_and_pool 的池化方式
+	Pooling PoolingMode `yaml:"pooling"`
+}
+
+// Enabled 是否设置了长度限制
+func (p OverflowPolicy) Enabled() bool {
+	return p.MaxChars > 0 || p.MaxTokens > 0
+}
+
+// Validate 检查策略配置
+func (p OverflowPolicy) Validate() error {
+	switch p.Mode {
+	case "", OverflowError, OverflowTruncateHead, OverflowTruncateTail, OverflowChunkAndPool:
+	default:
+		return fmt.Errorf("%w: unknown overflow mode: %s", ErrInvalidConfig, p.Mode)
+	}
+	switch p.Pooling {
+	case "", PoolingMean, PoolingWeighted:
+	default:
+		return fmt.Errorf("%w: unknown pooling mode: %s", ErrInvalidConfig, p.Pooling)
+	}
+	return nil
+}
+
+// ApproxTokens 估算文本的 token 数
+func ApproxTokens(text string) int {
+	var quarters int
+	for _, r := range text {
+		quarters += runeTokenCost(r)
+	}
+	return (quarters + 3) / 4
+}
+
+// runeTokenCost 单个字符的 token 成本，以 1/4 token 为单位
+func runeTokenCost(r rune) int {
+	if r < utf8.RuneSelf {
+		return 1
+	}
+	return 4
+}
+
+// fits 判断文本是否在限制之内
+func (p OverflowPolicy) fits(text string) bool {
+	if p.MaxChars > 0 && utf8.RuneCountInString(text) > p.MaxChars {
+		return false
+	}
+	if p.MaxTokens > 0 && ApproxTokens(text) > p.MaxTokens {
+		return false
+	}
+	return true
+}
+
+// prefixLen 返回从 runes[start:] 开始能放入限制的最多字符数
+func (p OverflowPolicy) prefixLen(runes []rune, start int) int {
+	quarters := 0
+	n := 0
+	for i := start; i < len(runes); i++ {
+		if p.MaxChars > 0 && n+1 > p.MaxChars {
+			break
+		}
+		cost := runeTokenCost(runes[i])
+		if p.MaxTokens > 0 && quarters+cost > p.MaxTokens*4 {
+			break
+		}
+		quarters += cost
+		n++
+	}
+	return n
+}
+
+// suffixLen 返回从末尾向前能放入限制的最多字符数
+func (p OverflowPolicy) suffixLen(runes []rune) int {
+	quarters := 0
+	n := 0
+	for i := len(runes) - 1; i >= 0; i-- {
+		if p.MaxChars > 0 && n+1 > p.MaxChars {
+			break
+		}
+		cost := runeTokenCost(runes[i])
+		if p.MaxTokens > 0 && quarters+cost > p.MaxTokens*4 {
+			break
+		}
+		quarters += cost
+		n++
+	}
+	return n
+}
+
+// reserve 返回为前缀等附加文本预留长度后的策略，限制至少保留 1
+func (p OverflowPolicy) reserve(overhead string) OverflowPolicy {
+	if overhead == "" {
+		return p
+	}
+	if p.MaxChars > 0 {
+		p.MaxChars = max(p.MaxChars-utf8.RuneCountInString(overhead), 1)
+	}
+	if p.MaxTokens > 0 {
+		p.MaxTokens = max(p.MaxTokens-ApproxTokens(overhead), 1)
+	}
+	return p
+}
+
+// chunk 将文本切分为都在限制之内的片段，尽量在空白处断开
+func (p OverflowPolicy) chunk(text string) []string {
+	runes := []rune(text)
+	var chunks []string
+	for start := 0; start < len(runes); {
+		n := p.prefixLen(runes, start)
+		if n == 0 {
+			// 单个字符已超出限制，无法再切分
+			n = 1
+		}
+		end := start + n
+		if end < len(runes) {
+			// 在后半段寻找空白作为断点，避免切断单词
+			for i := end; i > start+n/2; i-- {
+				if unicode.IsSpace(runes[i-1]) {
+					end = i
+					break
+				}
+			}
+		}
+		chunks = append(chunks, string(runes[start:end]))
+		start = end
+	}
+	return chunks
+}
+
+// OverflowEmbedder 按 OverflowPolicy 处理超长输入的装饰器
+// 使用 Config.Overflow 通过工厂创建时会自动包装
+// 内部服务为 InstructionEmbedder 时，EmbedQuery / EmbedDocuments 为前缀预留长度，
+// 截断或切分后的每一段都会加上前缀
+type OverflowEmbedder struct {
+	inner  Embedder
+	policy OverflowPolicy
+	prompt PromptTemplate
+	logger *Logger
+}
+
+// NewOverflowEmbedder 使用超长输入策略包装嵌入服务
+func NewOverflowEmbedder(inner Embedder, policy OverflowPolicy) *OverflowEmbedder {
+	o := &OverflowEmbedder{
+		inner:  inner,
+		policy: policy,
+		logger: NewLogger("overflow-embedder"),
+	}
+	if instruction, ok := inner.(*InstructionEmbedder); ok {
+		o.prompt = instruction.template
+	}
+	return o
+}
+
+// Embed 批量嵌入多个文本
+func (o *OverflowEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return o.embed(texts, o.policy, func(inputs []string) ([][]float32, error) {
+		return o.inner.Embed(ctx, inputs)
+	})
+}
+
+// EmbedSingle 嵌入单个文本
+func (o *OverflowEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	embeddings, err := o.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// BatchEmbed 分批处理大量文本，切分后的片段一起参与分批
+func (o *OverflowEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	return o.embed(texts, o.policy, func(inputs []string) ([][]float32, error) {
+		return o.inner.BatchEmbed(ctx, inputs, batchSize)
+	})
+}
+
+// EmbedQuery 以查询模式嵌入文本，切分后的每个片段都按查询模式嵌入
+func (o *OverflowEmbedder) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	policy := o.policy.reserve(applyTemplate(o.prompt.Query, ""))
+	embeddings, err := o.embed([]string{query}, policy, func(inputs []string) ([][]float32, error) {
+		result := make([][]float32, len(inputs))
+		for i, input := range inputs {
+			embedding, err := EmbedQuery(ctx, o.inner, input)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = embedding
+		}
+		return result, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return embeddings[0], nil
+}
+
+// EmbedDocuments 以文档模式嵌入多个文本，切分后的每个片段都按文档模式嵌入
+func (o *OverflowEmbedder) EmbedDocuments(ctx context.Context, documents []string) ([][]float32, error) {
+	policy := o.policy.reserve(applyTemplate(o.prompt.Document, ""))
+	return o.embed(documents, policy, func(inputs []string) ([][]float32, error) {
+		return EmbedDocuments(ctx, o.inner, inputs)
+	})
+}
+
+// GetDimension 获取嵌入维度
+func (o *OverflowEmbedder) GetDimension() int {
+	return o.inner.GetDimension()
+}
+
+// GetModel 获取模型名称
+func (o *OverflowEmbedder) GetModel() string {
+	return o.inner.GetModel()
+}
+
+// Health 健康检查
+func (o *OverflowEmbedder) Health(ctx context.Context) error {
+	return o.inner.Health(ctx)
+}
+
+// embed 按 policy 展开超长文本，调用 embed 后将片段向量池化回原位置（私有方法）
+func (o *OverflowEmbedder) embed(texts []string, policy OverflowPolicy, embed func(inputs []string) ([][]float32, error)) ([][]float32, error) {
+	inputs := make([]string, 0, len(texts))
+	// spans[i] 为第 i 个文本在 inputs 中对应的片段范围
+	spans := make([][2]int, len(texts))
+	chunked := false
+
+	for i, text := range texts {
+		start := len(inputs)
+		switch {
+		case policy.fits(text):
+			inputs = append(inputs, text)
+		case policy.Mode == OverflowTruncateHead:
+			runes := []rune(text)
+			inputs = append(inputs, string(runes[len(runes)-policy.suffixLen(runes):]))
+		case policy.Mode == OverflowTruncateTail:
+			runes := []rune(text)
+			inputs = append(inputs, string(runes[:policy.prefixLen(runes, 0)]))
+		case policy.Mode == OverflowChunkAndPool:
+			inputs = append(inputs, policy.chunk(text)...)
+			chunked = true
+		default:
+			return nil, fmt.Errorf("%w: text at index %d exceeds limit (%d chars, ~%d tokens)",
+				ErrInputTooLong, i, utf8.RuneCountInString(text), ApproxTokens(text))
+		}
+		spans[i] = [2]int{start, len(inputs)}
+	}
+
+	if chunked {
+		o.logger.Debug("超长文本已切分", Int("texts", len(texts)), Int("inputs", len(inputs)))
+	}
+
+	embeddings, err := embed(inputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) != len(inputs) {
+		return nil, fmt.Errorf("%w: expected %d embeddings, got %d", ErrInvalidResponse, len(inputs), len(embeddings))
+	}
+	if !chunked {
+		return embeddings, nil
+	}
+
+	result := make([][]float32, len(texts))
+	for i, span := range spans {
+		if span[1]-span[0] == 1 {
+			result[i] = embeddings[span[0]]
+			continue
+		}
+		result[i] = o.pool(inputs[span[0]:span[1]], embeddings[span[0]:span[1]])
+	}
+	return result, nil
+}
+
+// pool 将多个片段向量合并为一个（私有方法）
+func (o *OverflowEmbedder) pool(chunks []string, vectors [][]float32) []float32 {
+	pooled := make([]float32, len(vectors[0]))
+	var total float64
+	for i, vector := range vectors {
+		weight := 1.0
+		if o.policy.Pooling == PoolingWeighted {
+			weight = float64(utf8.RuneCountInString(chunks[i]))
+		}
+		total += weight
+		for j, v := range vector {
+			if j < len(pooled) {
+				pooled[j] += float32(weight) * v
+			}
+		}
+	}
+	for j := range pooled {
+		pooled[j] = float32(float64(pooled[j]) / total)
+	}
+	return pooled
+}
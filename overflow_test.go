@@ -0,0 +1,151 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOverflowPolicyLimits(t *testing.T) {
+	if got := ApproxTokens("abcdefgh"); got != 2 {
+		t.Errorf("Expected 2 tokens for 8 ASCII chars, got %d", got)
+	}
+	if got := ApproxTokens("你好世界"); got != 4 {
+		t.Errorf("Expected 4 tokens for 4 CJK chars, got %d", got)
+	}
+
+	policy := OverflowPolicy{MaxChars: 10, MaxTokens: 2}
+	if !policy.fits("abcdefgh") {
+		t.Error("Expected 8 ASCII chars to fit")
+	}
+	if policy.fits("你好世") {
+		t.Error("Expected 3 CJK chars to exceed 2 tokens")
+	}
+}
+
+func TestOverflowEmbedderModes(t *testing.T) {
+	long := "hello world foo"
+	cases := []struct {
+		mode OverflowMode
+		want []string
+	}{
+		{OverflowTruncateTail, []string{"hello worl"}},
+		{OverflowTruncateHead, []string{" world foo"}},
+		{OverflowChunkAndPool, []string{"hello ", "world foo"}},
+	}
+
+	for _, c := range cases {
+		inner := &countingEmbedder{}
+		embedder := NewOverflowEmbedder(inner, OverflowPolicy{Mode: c.mode, MaxChars: 10})
+
+		embeddings, err := embedder.Embed(context.Background(), []string{"short", long})
+		if err != nil {
+			t.Fatalf("%s: Embed failed: %v", c.mode, err)
+		}
+		if len(embeddings) != 2 {
+			t.Fatalf("%s: Expected 2 embeddings, got %d", c.mode, len(embeddings))
+		}
+		got := strings.Join(inner.received[1:], "|")
+		if got != strings.Join(c.want, "|") {
+			t.Errorf("%s: Expected inputs %q, got %q", c.mode, c.want, inner.received[1:])
+		}
+		if embeddings[0][0] != 5 {
+			t.Errorf("%s: Short text should pass through unchanged, got %v", c.mode, embeddings[0])
+		}
+	}
+}
+
+func TestOverflowEmbedderPooling(t *testing.T) {
+	inner := &countingEmbedder{}
+	text := strings.Repeat("a", 6) + strings.Repeat("b", 2)
+
+	embedder := NewOverflowEmbedder(inner, OverflowPolicy{Mode: OverflowChunkAndPool, MaxChars: 6})
+	embeddings, _ := embedder.EmbedSingle(context.Background(), text)
+	if embeddings[0] != 4 {
+		t.Errorf("Expected mean pooled value 4, got %v", embeddings[0])
+	}
+
+	embedder = NewOverflowEmbedder(inner, OverflowPolicy{Mode: OverflowChunkAndPool, MaxChars: 6, Pooling: PoolingWeighted})
+	embeddings, _ = embedder.EmbedSingle(context.Background(), text)
+	if embeddings[0] != 5 {
+		t.Errorf("Expected length-weighted pooled value 5, got %v", embeddings[0])
+	}
+}
+
+func TestOverflowEmbedderError(t *testing.T) {
+	embedder := NewOverflowEmbedder(&countingEmbedder{}, OverflowPolicy{MaxTokens: 1})
+	_, err := embedder.Embed(context.Background(), []string{"ok", "too long"})
+	if !errors.Is(err, ErrInputTooLong) || !strings.Contains(err.Error(), "index 1") {
+		t.Errorf("Expected ErrInputTooLong at index 1, got %v", err)
+	}
+}
+
+func TestFactoryAppliesOverflowPolicy(t *testing.T) {
+	factory := NewFactory()
+	factory.RegisterProvider("mock", func(config Config) (Embedder, error) {
+		return &MockEmbedder{}, nil
+	})
+
+	embedder, err := factory.CreateWithConfig(Config{Provider: "mock", Overflow: OverflowPolicy{MaxChars: 100}})
+	if err != nil {
+		t.Fatalf("CreateWithConfig failed: %v", err)
+	}
+	if _, ok := embedder.(*OverflowEmbedder); !ok {
+		t.Errorf("Expected *OverflowEmbedder, got %T", embedder)
+	}
+
+	_, err = factory.CreateWithConfig(Config{Provider: "mock", Overflow: OverflowPolicy{Mode: "drop", MaxChars: 100}})
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig for unknown mode, got %v", err)
+	}
+}
+
+func TestFactoryOverflowKeepsPromptPrefix(t *testing.T) {
+	for _, mode := range []OverflowMode{OverflowTruncateHead, OverflowChunkAndPool} {
+		inner := &countingEmbedder{}
+		factory := NewFactory()
+		factory.RegisterProvider("mock", func(config Config) (Embedder, error) {
+			return inner, nil
+		})
+
+		embedder, err := factory.CreateWithConfig(Config{
+			Provider: "mock",
+			Model:    "nomic-embed-text",
+			Overflow: OverflowPolicy{Mode: mode, MaxChars: 20},
+		})
+		if err != nil {
+			t.Fatalf("%s: CreateWithConfig failed: %v", mode, err)
+		}
+
+		ctx := context.Background()
+		long := strings.Repeat("word ", 10)
+		if _, err := EmbedQuery(ctx, embedder, long); err != nil {
+			t.Fatalf("%s: EmbedQuery failed: %v", mode, err)
+		}
+		if _, err := EmbedDocuments(ctx, embedder, []string{long}); err != nil {
+			t.Fatalf("%s: EmbedDocuments failed: %v", mode, err)
+		}
+
+		queries, documents := 0, 0
+		for _, text := range inner.received {
+			if len(text) > 20 {
+				t.Errorf("%s: input %q exceeds the limit including its prefix", mode, text)
+			}
+			switch {
+			case strings.HasPrefix(text, "search_query: "):
+				queries++
+			case strings.HasPrefix(text, "search_document: "):
+				documents++
+			default:
+				t.Errorf("%s: input %q lost its prefix", mode, text)
+			}
+		}
+		if queries == 0 || documents == 0 {
+			t.Errorf("%s: expected prefixed queries and documents, got %q", mode, inner.received)
+		}
+		if mode == OverflowChunkAndPool && (queries < 2 || documents < 2) {
+			t.Errorf("%s: expected every chunk to be prefixed, got %q", mode, inner.received)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package embedder
+
+import (
+	"context"
+	"math"
+)
+
+// Stage 向量后处理阶段
+// Apply 可以原地修改向量，装饰器会先复制内部服务返回的结果
+type Stage interface {
+	// Apply 处理单个向量并返回结果
+	Apply(vector []float32) []float32
+
+	// Dimension 根据输入维度返回输出维度
+	Dimension(in int) int
+}
+
+// normalizeStage L2归一化
+type normalizeStage struct{}
+
+// Normalize 创建L2归一化阶段，零向量保持不变
+func Normalize() Stage {
+	return normalizeStage{}
+}
+
+func (normalizeStage) Apply(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	if sum == 0 {
+		return vector
+	}
+	inv := float32(1 / math.Sqrt(sum))
+	for i := range vector {
+		vector[i] *= inv
+	}
+	return vector
+}
+
+func (normalizeStage) Dimension(in int) int {
+	return in
+}
+
+// truncateStage 截断维度
+type truncateStage struct {
+	dims int
+}
+
+// TruncateDims 创建维度截断阶段，适用于 Matryoshka 训练的模型
+// 截断后向量不再是单位长度，通常需要再接一个 Normalize
+func TruncateDims(dims int) Stage {
+	return truncateStage{dims: dims}
+}
+
+func (s truncateStage) Apply(vector []float32) []float32 {
+	if s.dims > 0 && len(vector) > s.dims {
+		return vector[:s.dims]
+	}
+	return vector
+}
+
+func (s truncateStage) Dimension(in int) int {
+	if s.dims > 0 && in > s.dims {
+		return s.dims
+	}
+	return in
+}
+
+// Int8Vector int8量化向量，原始值约等于 Values[i] * Scale
+type Int8Vector struct {
+	Values []int8
+	Scale  float32
+}
+
+// QuantizeInt8 按向量最大绝对值对称量化到 int8
+func QuantizeInt8(vector []float32) Int8Vector {
+	var maxAbs float32
+	for _, v := range vector {
+		if v < 0 {
+			v = -v
+		}
+		if v > maxAbs {
+			maxAbs = v
+		}
+	}
+
+	result := Int8Vector{Values: make([]int8, len(vector))}
+	if maxAbs == 0 {
+		return result
+	}
+	result.Scale = maxAbs / 127
+	for i, v := range vector {
+		result.Values[i] = int8(math.Round(float64(v / result.Scale)))
+	}
+	return result
+}
+
+// Dequantize 还原为 float32 向量
+func (q Int8Vector) Dequantize() []float32 {
+	result := make([]float32, len(q.Values))
+	for i, v := range q.Values {
+		result[i] = float32(v) * q.Scale
+	}
+	return result
+}
+
+// QuantizeBinary 二值量化：大于 0 的维度为 1，按高位在前打包为字节
+// 结果长度为 ceil(len(vector)/8)，可用汉明距离比较
+func QuantizeBinary(vector []float32) []byte {
+	result := make([]byte, (len(vector)+7)/8)
+	for i, v := range vector {
+		if v > 0 {
+			result[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return result
+}
+
+// PostProcessEmbedder 依次对嵌入结果应用后处理阶段的装饰器
+type PostProcessEmbedder struct {
+	inner  Embedder
+	stages []Stage
+}
+
+// NewPostProcessEmbedder 使用后处理阶段包装嵌入服务
+//
+//	e := NewPostProcessEmbedder(inner, TruncateDims(256), Normalize())
+func NewPostProcessEmbedder(inner Embedder, stages ...Stage) *PostProcessEmbedder {
+	return &PostProcessEmbedder{inner: inner, stages: stages}
+}
+
+// Embed 批量嵌入多个文本
+func (p *PostProcessEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings, err := p.inner.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	return p.applyAll(embeddings), nil
+}
+
+// EmbedSingle 嵌入单个文本
+func (p *PostProcessEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	embedding, err := p.inner.EmbedSingle(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+	return p.apply(embedding), nil
+}
+
+// BatchEmbed 分批处理大量文本
+func (p *PostProcessEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	embeddings, err := p.inner.BatchEmbed(ctx, texts, batchSize)
+	if err != nil {
+		return nil, err
+	}
+	return p.applyAll(embeddings), nil
+}
+
+// EmbedQuery 以查询模式嵌入文本，内部服务不支持时直接嵌入
+func (p *PostProcessEmbedder) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	embedding, err := EmbedQuery(ctx, p.inner, query)
+	if err != nil {
+		return nil, err
+	}
+	return p.apply(embedding), nil
+}
+
+// EmbedDocuments 以文档模式嵌入多个文本，内部服务不支持时直接嵌入
+func (p *PostProcessEmbedder) EmbedDocuments(ctx context.Context, documents []string) ([][]float32, error) {
+	embeddings, err := EmbedDocuments(ctx, p.inner, documents)
+	if err != nil {
+		return nil, err
+	}
+	return p.applyAll(embeddings), nil
+}
+
+// EmbedInt8 嵌入并量化为 int8
+func (p *PostProcessEmbedder) EmbedInt8(ctx context.Context, texts []string) ([]Int8Vector, error) {
+	embeddings, err := p.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Int8Vector, len(embeddings))
+	for i, embedding := range embeddings {
+		result[i] = QuantizeInt8(embedding)
+	}
+	return result, nil
+}
+
+// EmbedBinary 嵌入并二值量化
+func (p *PostProcessEmbedder) EmbedBinary(ctx context.Context, texts []string) ([][]byte, error) {
+	embeddings, err := p.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	result := make([][]byte, len(embeddings))
+	for i, embedding := range embeddings {
+		result[i] = QuantizeBinary(embedding)
+	}
+	return result, nil
+}
+
+// GetDimension 获取后处理之后的维度
+func (p *PostProcessEmbedder) GetDimension() int {
+	dim := p.inner.GetDimension()
+	if dim == 0 {
+		return 0
+	}
+	for _, stage := range p.stages {
+		dim = stage.Dimension(dim)
+	}
+	return dim
+}
+
+// GetModel 获取模型名称
+func (p *PostProcessEmbedder) GetModel() string {
+	return p.inner.GetModel()
+}
+
+// Health 健康检查
+func (p *PostProcessEmbedder) Health(ctx context.Context) error {
+	return p.inner.Health(ctx)
+}
+
+// applyAll 处理一批向量（私有方法）
+func (p *PostProcessEmbedder) applyAll(embeddings [][]float32) [][]float32 {
+	result := make([][]float32, len(embeddings))
+	for i, embedding := range embeddings {
+		result[i] = p.apply(embedding)
+	}
+	return result
+}
+
+// apply 复制后依次应用各阶段，避免修改内部服务（如缓存）持有的向量（私有方法）
+func (p *PostProcessEmbedder) apply(embedding []float32) []float32 {
+	vector := make([]float32, len(embedding))
+	copy(vector, embedding)
+	for _, stage := range p.stages {
+		vector = stage.Apply(vector)
+	}
+	return vector
+}
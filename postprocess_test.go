@@ -0,0 +1,74 @@
+package embedder
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+// fixedEmbedder 对所有输入返回同一个向量
+type fixedEmbedder struct {
+	MockEmbedder
+	vector []float32
+}
+
+func (f *fixedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	result := make([][]float32, len(texts))
+	for i := range result {
+		result[i] = f.vector
+	}
+	return result, nil
+}
+
+func (f *fixedEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	return f.vector, nil
+}
+
+func (f *fixedEmbedder) GetDimension() int {
+	return len(f.vector)
+}
+
+func TestPostProcessEmbedderStages(t *testing.T) {
+	inner := &fixedEmbedder{vector: []float32{3, 4, 12, 0}}
+	embedder := NewPostProcessEmbedder(inner, TruncateDims(2), Normalize())
+
+	if embedder.GetDimension() != 2 {
+		t.Errorf("Expected post-processed dimension 2, got %d", embedder.GetDimension())
+	}
+
+	embedding, err := embedder.EmbedSingle(context.Background(), "text")
+	if err != nil {
+		t.Fatalf("EmbedSingle failed: %v", err)
+	}
+	if len(embedding) != 2 || math.Abs(float64(embedding[0])-0.6) > 1e-6 || math.Abs(float64(embedding[1])-0.8) > 1e-6 {
+		t.Errorf("Expected [0.6 0.8], got %v", embedding)
+	}
+	if inner.vector[0] != 3 {
+		t.Error("Post-processing must not modify the inner embedder's vectors")
+	}
+}
+
+func TestQuantization(t *testing.T) {
+	vector := []float32{0.5, -1, 0, 0.25, 0.1, -0.1, 0.2, 0.3, 0.9}
+
+	q := QuantizeInt8(vector)
+	if q.Values[1] != -127 || q.Values[0] != 64 {
+		t.Errorf("Unexpected int8 values: %v", q.Values)
+	}
+	for i, v := range q.Dequantize() {
+		if math.Abs(float64(v-vector[i])) > 0.01 {
+			t.Errorf("Dequantized value %d too far: %v vs %v", i, v, vector[i])
+		}
+	}
+
+	bits := QuantizeBinary(vector)
+	if len(bits) != 2 || bits[0] != 0b10011011 || bits[1] != 0b10000000 {
+		t.Errorf("Unexpected binary quantization: %08b", bits)
+	}
+
+	embedder := NewPostProcessEmbedder(&fixedEmbedder{vector: vector})
+	packed, err := embedder.EmbedBinary(context.Background(), []string{"a", "b"})
+	if err != nil || len(packed) != 2 || packed[1][0] != bits[0] {
+		t.Errorf("EmbedBinary returned %v, %v", packed, err)
+	}
+}
@@ -0,0 +1,272 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy 瞬时错误的重试策略：指数退避 + 抖动
+// 零值表示不重试，provider 可通过 Do 复用同一套策略
+type RetryPolicy struct {
+	// MaxAttempts 最大尝试次数（包含首次请求），<=1 表示不重试
+	MaxAttempts int `yaml:"max_attempts"`
+	// BaseDelay 首次重试前的等待时间，之后每次翻倍
+	BaseDelay time.Duration `yaml:"base_delay"`
+	// MaxDelay 单次等待时间上限（不限制服务端 Retry-After）
+	MaxDelay time.Duration `yaml:"max_delay"`
+	// Jitter 抖动比例 [0,1]，等待时间在 [delay*(1-Jitter), delay] 之间随机
+	Jitter float64 `yaml:"jitter"`
+	// RetryableStatusCodes 可重试的HTTP状态码，为空时使用默认列表
+	RetryableStatusCodes []int `yaml:"retryable_status_codes"`
+
+	// OnRetry 每次重试前的回调，用于日志或监控
+	OnRetry func(attempt int, err error, delay time.Duration) `yaml:"-"`
+
+	// logger 未设置 OnRetry 时记录重试日志，由 provider 设置
+	logger *Logger
+}
+
+// DefaultRetryPolicy 默认重试策略
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:          3,
+	BaseDelay:            200 * time.Millisecond,
+	MaxDelay:             5 * time.Second,
+	Jitter:               0.2,
+	RetryableStatusCodes: defaultRetryableStatusCodes,
+}
+
+// defaultRetryableStatusCodes 默认可重试的HTTP状态码
+var defaultRetryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// statusCoder 携带HTTP状态码的错误
+// 自定义 provider 返回实现该接口的错误即可参与按状态码重试
+type statusCoder interface {
+	HTTPStatusCode() int
+}
+
+// retryAfterer 携带服务端建议等待时间（Retry-After）的错误
+type retryAfterer interface {
+	RetryAfter() time.Duration
+}
+
+// Do 按策略执行 fn，遇到可重试错误时退避后重试，返回最后一次的错误
+func (p RetryPolicy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	attempts := p.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = fn(withAttempt(ctx, attempt))
+		if err == nil || attempt >= attempts || !p.Retryable(err) {
+			return err
+		}
+
+		delay := p.backoff(attempt, err)
+		countRetry(ctx)
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, delay)
+		} else if p.logger != nil {
+			p.logger.WarnContext(ctx, "请求失败，准备重试",
+				Error(err),
+				Int("attempt", attempt),
+				Duration("delay", delay))
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// Retryable 判断错误是否值得重试：可重试状态码、ErrUnavailable / ErrRateLimited 及网络错误
+func (p RetryPolicy) Retryable(err error) bool {
+	if err == nil || errors.Is(err, ErrInvalidResponse) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	var coder statusCoder
+	if errors.As(err, &coder) && coder.HTTPStatusCode() > 0 {
+		codes := p.RetryableStatusCodes
+		if len(codes) == 0 {
+			codes = defaultRetryableStatusCodes
+		}
+		status := coder.HTTPStatusCode()
+		for _, code := range codes {
+			if code == status {
+				return true
+			}
+		}
+		return false
+	}
+
+	// 客户端超时也表现为 DeadlineExceeded，只有调用方取消时才放弃
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return errors.Is(err, ErrUnavailable)
+	}
+
+	// 没有状态码时只重试服务不可用、限流及连接失败等网络错误，其他错误（如配置错误）原样返回
+	if errors.Is(err, ErrUnavailable) || errors.Is(err, ErrRateLimited) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// backoff 计算第 attempt 次失败后的等待时间（私有方法）
+func (p RetryPolicy) backoff(attempt int, err error) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+
+	if p.Jitter > 0 && delay > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay -= time.Duration(rand.Float64() * jitter * float64(delay))
+	}
+
+	var ra retryAfterer
+	if errors.As(err, &ra) && ra.RetryAfter() > delay {
+		delay = ra.RetryAfter()
+	}
+	return delay
+}
+
+// withRetryLogging 为策略补充重试日志，设置了 OnRetry 时由回调负责（私有方法）
+func withRetryLogging(policy RetryPolicy, logger *Logger) RetryPolicy {
+	policy.logger = logger
+	return policy
+}
+
+// parseRetryAfter 解析 Retry-After 响应头，支持秒数和HTTP日期两种格式
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// RetryEmbedder 为任意 Embedder 增加重试的装饰器
+// 适用于自身没有内置重试的自定义 provider
+type RetryEmbedder struct {
+	inner  Embedder
+	policy RetryPolicy
+}
+
+// NewRetryEmbedder 使用重试策略包装嵌入服务
+func NewRetryEmbedder(inner Embedder, policy RetryPolicy) *RetryEmbedder {
+	return &RetryEmbedder{inner: inner, policy: policy}
+}
+
+// Embed 批量嵌入多个文本，失败时整批重试
+func (r *RetryEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var result [][]float32
+	err := r.policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = r.inner.Embed(ctx, texts)
+		return err
+	})
+	return result, err
+}
+
+// EmbedSingle 嵌入单个文本
+func (r *RetryEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	var result []float32
+	err := r.policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = r.inner.EmbedSingle(ctx, text)
+		return err
+	})
+	return result, err
+}
+
+// BatchEmbed 分批处理大量文本，每批独立重试
+func (r *RetryEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	if batchSize <= 0 {
+		batchSize = len(texts)
+	}
+
+	var allEmbeddings [][]float32
+	for i := 0; i < len(texts); i += batchSize {
+		end := i + batchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		embeddings, err := r.Embed(ctx, texts[i:end])
+		if err != nil {
+			return nil, err
+		}
+
+		allEmbeddings = append(allEmbeddings, embeddings...)
+	}
+
+	return allEmbeddings, nil
+}
+
+// EmbedQuery 以查询模式嵌入文本，内部服务不支持时直接嵌入
+func (r *RetryEmbedder) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	var result []float32
+	err := r.policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = EmbedQuery(ctx, r.inner, query)
+		return err
+	})
+	return result, err
+}
+
+// EmbedDocuments 以文档模式嵌入多个文本，失败时整批重试
+func (r *RetryEmbedder) EmbedDocuments(ctx context.Context, documents []string) ([][]float32, error) {
+	var result [][]float32
+	err := r.policy.Do(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = EmbedDocuments(ctx, r.inner, documents)
+		return err
+	})
+	return result, err
+}
+
+// GetDimension 获取嵌入维度
+func (r *RetryEmbedder) GetDimension() int {
+	return r.inner.GetDimension()
+}
+
+// GetModel 获取模型名称
+func (r *RetryEmbedder) GetModel() string {
+	return r.inner.GetModel()
+}
+
+// Health 健康检查（不重试，保持快速失败）
+func (r *RetryEmbedder) Health(ctx context.Context) error {
+	return r.inner.Health(ctx)
+}
@@ -0,0 +1,214 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyRetriesTransientErrors(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+
+	var calls int
+	err := policy.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return &ProviderError{StatusCode: http.StatusServiceUnavailable, Index: -1}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected success after retries, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+
+	calls = 0
+	err = policy.Do(context.Background(), func(ctx context.Context) error {
+		calls++
+		return &ProviderError{StatusCode: http.StatusBadRequest, Index: -1}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Expected non-retryable error after 1 attempt, got %v after %d", err, calls)
+	}
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"status 503", &ProviderError{StatusCode: http.StatusServiceUnavailable, Index: -1}, true},
+		{"status 400", &ProviderError{StatusCode: http.StatusBadRequest, Index: -1}, false},
+		{"unavailable", fmt.Errorf("%w: connection refused", ErrUnavailable), true},
+		{"rate limited", &ProviderError{Kind: ErrRateLimited, Index: -1}, true},
+		{"network", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{"client timeout", &ProviderError{Kind: ErrUnavailable, Index: -1, Err: context.DeadlineExceeded}, true},
+		{"caller canceled", context.Canceled, false},
+		{"invalid config", &ProviderError{Kind: ErrInvalidConfig, Index: -1}, false},
+		{"model not found", fmt.Errorf("%w: %s", ErrModelNotFound, "missing"), false},
+		{"invalid response", &ProviderError{Kind: ErrInvalidResponse, Index: -1}, false},
+		{"unknown", errors.New("boom"), false},
+	}
+	for _, c := range cases {
+		if got := DefaultRetryPolicy.Retryable(c.err); got != c.want {
+			t.Errorf("%s: Retryable(%v) = %v, want %v", c.name, c.err, got, c.want)
+		}
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: 300 * time.Millisecond}
+	err := errors.New("connection refused")
+
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for i, want := range expected {
+		if got := policy.backoff(i+1, err); got != want {
+			t.Errorf("Attempt %d: expected delay %v, got %v", i+1, want, got)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 20; i++ {
+		if got := policy.backoff(1, err); got < 50*time.Millisecond || got > 100*time.Millisecond {
+			t.Fatalf("Jittered delay out of range: %v", got)
+		}
+	}
+
+	// 服务端 Retry-After 优先于更短的退避时间
+	statusErr := &ProviderError{StatusCode: http.StatusTooManyRequests, Index: -1, retryAfter: 2 * time.Second}
+	if got := policy.backoff(1, statusErr); got != 2*time.Second {
+		t.Errorf("Expected Retry-After delay 2s, got %v", got)
+	}
+}
+
+func TestRetryPolicyStopsOnContextCancel(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	var calls int
+	err := policy.Do(ctx, func(ctx context.Context) error {
+		calls++
+		return &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}
+	})
+	if err == nil || calls != 1 {
+		t.Errorf("Expected to give up after cancellation, got %v after %d attempts", err, calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := parseRetryAfter("3"); got != 3*time.Second {
+		t.Errorf("Expected 3s, got %v", got)
+	}
+	if got := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)); got <= 0 || got > time.Minute {
+		t.Errorf("Expected delay up to 1m, got %v", got)
+	}
+	if got := parseRetryAfter("invalid"); got != 0 {
+		t.Errorf("Expected 0 for invalid value, got %v", got)
+	}
+}
+
+func TestOllamaEmbedderRetriesServerErrors(t *testing.T) {
+	var embedCalls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		// 前两次模拟模型重新加载
+		if atomic.AddInt32(&embedCalls, 1) <= 2 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"embeddings": [[0.1, 0.2]]}`))
+	}))
+	defer server.Close()
+
+	var retries int
+	embedder := &OllamaEmbedder{
+		baseURL:    server.URL,
+		model:      "test-model",
+		httpClient: server.Client(),
+		retry: RetryPolicy{
+			MaxAttempts: 3,
+			BaseDelay:   time.Millisecond,
+			OnRetry:     func(int, error, time.Duration) { retries++ },
+		},
+		logger: NewLogger("test"),
+	}
+
+	if _, err := embedder.EmbedSingle(context.Background(), "text"); err != nil {
+		t.Fatalf("Expected success after retries, got %v", err)
+	}
+	if retries != 2 {
+		t.Errorf("Expected 2 retries, got %d", retries)
+	}
+}
+
+func TestRetryEmbedderWrapsCustomProvider(t *testing.T) {
+	inner := &flakyEmbedder{failures: 2}
+	embedder := NewRetryEmbedder(inner, RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+	embeddings, err := embedder.BatchEmbed(context.Background(), []string{"a", "b", "c"}, 2)
+	if err != nil {
+		t.Fatalf("BatchEmbed failed: %v", err)
+	}
+	if len(embeddings) != 3 {
+		t.Errorf("Expected 3 embeddings, got %d", len(embeddings))
+	}
+	if inner.calls != 4 {
+		t.Errorf("Expected 4 calls (2 failures + 2 batches), got %d", inner.calls)
+	}
+}
+
+// flakyEmbedder 前 failures 次调用返回网络错误的模拟嵌入服务
+type flakyEmbedder struct {
+	MockEmbedder
+	failures int
+	calls    int
+}
+
+func (f *flakyEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	f.calls++
+	if f.calls <= f.failures {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	}
+	return f.MockEmbedder.Embed(ctx, texts)
+}
+
+func TestLoadConfigMergesRetryDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "embedder.yaml")
+	data := `
+provider: ollama
+retry:
+  base_delay: 1s
+  jitter: 0
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	retry := config.Retry
+	if retry.BaseDelay != time.Second || retry.Jitter != 0 {
+		t.Errorf("Expected explicit retry fields to be kept, got %+v", retry)
+	}
+	if retry.MaxAttempts != DefaultRetryPolicy.MaxAttempts || retry.MaxDelay != DefaultRetryPolicy.MaxDelay {
+		t.Errorf("Expected missing retry fields to use defaults, got %+v", retry)
+	}
+}
@@ -0,0 +1,233 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultStreamBatchSize 流式嵌入的默认批大小
+const DefaultStreamBatchSize = 32
+
+// StreamOptions 流式嵌入选项
+type StreamOptions struct {
+	// BatchSize 每次请求的文本数，<=0 时使用 DefaultStreamBatchSize
+	BatchSize int
+	// Parallelism 同时进行的请求数，<=0 时为 1
+	Parallelism int
+	// FlushInterval 输入较慢时，未满的批次最多等待多久就发送，0 表示等到批次填满或输入关闭
+	FlushInterval time.Duration
+	// Documents 使用 EmbedDocuments（文档模式），否则使用 Embed
+	Documents bool
+}
+
+// StreamResult 流式嵌入的单条结果
+type StreamResult struct {
+	// Index 输入中的序号，从 0 开始
+	Index int
+	// Embedding 嵌入向量，失败时为 nil
+	Embedding []float32
+	// Err 该条输入的错误
+	Err error
+}
+
+// streamBatch 一个批次及其结果
+type streamBatch struct {
+	start int
+	texts []string
+	done  chan streamBatchResult
+}
+
+// streamBatchResult 批次的嵌入结果
+type streamBatchResult struct {
+	embeddings [][]float32
+	errs       []error
+}
+
+// EmbedStream 流式嵌入：从 texts 读取输入，按输入顺序输出结果
+//
+//	results := embedder.EmbedStream(ctx, e, lines, embedder.StreamOptions{BatchSize: 64, Parallelism: 4})
+//	for r := range results {
+//		if r.Err != nil { ... }
+//		sink.Write(r.Index, r.Embedding)
+//	}
+//
+// 调用方需要在输入结束后关闭 texts。单个批次失败只影响该批次的结果，后续输入继续处理；
+// 错误指向某条输入（如超长）时逐条重试该批次，只有出错的输入带有 Err。
+// 同时处理中的批次最多为 Parallelism 个，结果消费不及时会停止读取输入（背压）。
+// ctx 取消后停止读取输入并关闭结果通道，调用方可通过 ctx.Err() 判断是否完整。
+func EmbedStream(ctx context.Context, e Embedder, texts <-chan string, opts StreamOptions) <-chan StreamResult {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultStreamBatchSize
+	}
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = 1
+	}
+
+	work := make(chan *streamBatch)
+	// pending 按输入顺序排队等待输出的批次，容量限制了处理中的批次数
+	pending := make(chan *streamBatch, opts.Parallelism)
+	results := make(chan StreamResult, opts.BatchSize)
+
+	for w := 0; w < opts.Parallelism; w++ {
+		go func() {
+			for batch := range work {
+				batch.done <- embedStreamBatch(ctx, e, batch.texts, opts.Documents)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(work)
+		defer close(pending)
+		readStreamBatches(ctx, texts, opts, func(batch *streamBatch) bool {
+			select {
+			case pending <- batch:
+			case <-ctx.Done():
+				return false
+			}
+			select {
+			case work <- batch:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		})
+	}()
+
+	go func() {
+		defer close(results)
+		for batch := range pending {
+			var result streamBatchResult
+			select {
+			case result = <-batch.done:
+			case <-ctx.Done():
+				return
+			}
+
+			for i := range batch.texts {
+				r := StreamResult{Index: batch.start + i, Err: result.errs[i]}
+				if r.Err == nil {
+					r.Embedding = result.embeddings[i]
+				}
+				select {
+				case results <- r:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return results
+}
+
+// readStreamBatches 从输入读取文本并按批次交给 emit，emit 返回 false 时停止
+func readStreamBatches(ctx context.Context, texts <-chan string, opts StreamOptions, emit func(*streamBatch) bool) {
+	var (
+		batch []string
+		start int
+		timer *time.Timer
+		flush <-chan time.Time
+	)
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	send := func() bool {
+		if timer != nil {
+			timer.Stop()
+			flush = nil
+		}
+		if len(batch) == 0 {
+			return true
+		}
+		b := &streamBatch{start: start, texts: batch, done: make(chan streamBatchResult, 1)}
+		start += len(batch)
+		batch = make([]string, 0, opts.BatchSize)
+		return emit(b)
+	}
+
+	for {
+		select {
+		case text, ok := <-texts:
+			if !ok {
+				send()
+				return
+			}
+			batch = append(batch, text)
+			if len(batch) >= opts.BatchSize {
+				if !send() {
+					return
+				}
+			} else if len(batch) == 1 && opts.FlushInterval > 0 {
+				if timer == nil {
+					timer = time.NewTimer(opts.FlushInterval)
+				} else {
+					timer.Reset(opts.FlushInterval)
+				}
+				flush = timer.C
+			}
+		case <-flush:
+			flush = nil
+			if !send() {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// embedStreamBatch 嵌入一个批次，错误指向某条输入时逐条重试以隔离错误
+func embedStreamBatch(ctx context.Context, e Embedder, texts []string, documents bool) streamBatchResult {
+	embed := e.Embed
+	if documents {
+		embed = func(ctx context.Context, texts []string) ([][]float32, error) {
+			return EmbedDocuments(ctx, e, texts)
+		}
+	}
+
+	result := streamBatchResult{errs: make([]error, len(texts))}
+	embeddings, err := embed(ctx, texts)
+	if err == nil && len(embeddings) != len(texts) {
+		err = fmt.Errorf("%w: expected %d embeddings, got %d", ErrInvalidResponse, len(texts), len(embeddings))
+	}
+	if err == nil {
+		result.embeddings = embeddings
+		return result
+	}
+
+	if len(texts) == 1 || ctx.Err() != nil || !inputError(err) {
+		for i := range result.errs {
+			result.errs[i] = err
+		}
+		return result
+	}
+
+	result.embeddings = make([][]float32, len(texts))
+	for i, text := range texts {
+		embedding, err := embed(ctx, []string{text})
+		if err == nil && len(embedding) != 1 {
+			err = fmt.Errorf("%w: expected 1 embedding, got %d", ErrInvalidResponse, len(embedding))
+		}
+		if err != nil {
+			result.errs[i] = err
+			continue
+		}
+		result.embeddings[i] = embedding[0]
+	}
+	return result
+}
+
+// inputError 判断错误是否由某条输入引起，而不是整个请求失败
+func inputError(err error) bool {
+	if errors.Is(err, ErrInputTooLong) {
+		return true
+	}
+	var providerErr *ProviderError
+	return errors.As(err, &providerErr) && providerErr.Index >= 0
+}
@@ -0,0 +1,151 @@
+package embedder
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// streamEmbedder 把数字文本嵌入为 [n]，随机延迟以打乱完成顺序，"bad" 返回指向该条输入的错误
+type streamEmbedder struct {
+	MockEmbedder
+	mu    sync.Mutex
+	calls [][]string
+}
+
+func (s *streamEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, texts)
+	s.mu.Unlock()
+
+	time.Sleep(time.Duration(rand.Intn(3)) * time.Millisecond)
+	result := make([][]float32, len(texts))
+	for i, text := range texts {
+		if text == "bad" {
+			return nil, &ProviderError{Provider: "mock", Index: i, Kind: ErrInputTooLong}
+		}
+		n, _ := strconv.Atoi(text)
+		result[i] = []float32{float32(n)}
+	}
+	return result, nil
+}
+
+func feed(texts []string) <-chan string {
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		for _, text := range texts {
+			ch <- text
+		}
+	}()
+	return ch
+}
+
+func TestEmbedStreamOrder(t *testing.T) {
+	inner := &streamEmbedder{}
+	texts := numbers(100)
+
+	var results []StreamResult
+	for r := range EmbedStream(context.Background(), inner, feed(texts), StreamOptions{BatchSize: 7, Parallelism: 4}) {
+		results = append(results, r)
+	}
+
+	if len(results) != len(texts) {
+		t.Fatalf("Expected %d results, got %d", len(texts), len(results))
+	}
+	for i, r := range results {
+		if r.Index != i || r.Err != nil || r.Embedding[0] != float32(i) {
+			t.Fatalf("Result %d out of order or wrong: %+v", i, r)
+		}
+	}
+	// 并发时批次的调用顺序不确定，只检查批次大小
+	short := 0
+	for _, call := range inner.calls {
+		if len(call) != 7 {
+			short++
+		}
+	}
+	if len(inner.calls) != 15 || short != 1 {
+		t.Errorf("Expected 15 batches with one short final batch, got %d batches, %d short", len(inner.calls), short)
+	}
+}
+
+func TestEmbedStreamIsolatesInputErrors(t *testing.T) {
+	inner := &streamEmbedder{}
+	texts := []string{"0", "1", "bad", "3", "4"}
+
+	var results []StreamResult
+	for r := range EmbedStream(context.Background(), inner, feed(texts), StreamOptions{BatchSize: 5}) {
+		results = append(results, r)
+	}
+
+	if len(results) != len(texts) {
+		t.Fatalf("Expected %d results, got %d", len(texts), len(results))
+	}
+	for i, r := range results {
+		if i == 2 {
+			if !errors.Is(r.Err, ErrInputTooLong) || r.Embedding != nil {
+				t.Errorf("Expected input error for bad text, got %+v", r)
+			}
+			continue
+		}
+		if r.Err != nil || r.Embedding[0] != float32(i) {
+			t.Errorf("Result %d should succeed, got %+v", i, r)
+		}
+	}
+}
+
+func TestEmbedStreamFlushInterval(t *testing.T) {
+	inner := &streamEmbedder{}
+	texts := make(chan string)
+	defer close(texts)
+
+	results := EmbedStream(context.Background(), inner, texts, StreamOptions{BatchSize: 10, FlushInterval: 10 * time.Millisecond})
+	texts <- "1"
+	select {
+	case r := <-results:
+		if r.Index != 0 || r.Embedding[0] != 1 {
+			t.Errorf("Unexpected result %+v", r)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected partial batch to be flushed")
+	}
+}
+
+func TestEmbedStreamBackpressure(t *testing.T) {
+	inner := &streamEmbedder{}
+	texts := make(chan string)
+	ctx, cancel := context.WithCancel(context.Background())
+	results := EmbedStream(ctx, inner, texts, StreamOptions{BatchSize: 2, Parallelism: 2})
+
+	// 不读取结果时，输入应在有限的数量后被阻塞
+	sent := 0
+	for ; sent < 100; sent++ {
+		select {
+		case texts <- strconv.Itoa(sent):
+			continue
+		case <-time.After(50 * time.Millisecond):
+		}
+		break
+	}
+	if sent >= 100 {
+		t.Fatal("Expected input to block when results are not consumed")
+	}
+
+	cancel()
+	deadline := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-results:
+			if !ok {
+				return
+			}
+		case <-deadline:
+			t.Fatal("Expected results to close after cancel")
+		}
+	}
+}
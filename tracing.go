@@ -0,0 +1,236 @@
+package embedder
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+// Span 名称，TracingEmbedder 和内置 provider 使用
+const (
+	SpanEmbed          = "embedder.embed"
+	SpanEmbedSingle    = "embedder.embed_single"
+	SpanBatchEmbed     = "embedder.batch_embed"
+	SpanEmbedQuery     = "embedder.embed_query"
+	SpanEmbedDocuments = "embedder.embed_documents"
+	SpanHealth         = "embedder.health"
+	// SpanHTTP provider 发出的每次HTTP请求（含每次重试）
+	SpanHTTP = "embedder.http"
+)
+
+// Span 属性键，供 Tracer 实现统一命名
+const (
+	AttrProvider  = "embedder.provider"
+	AttrModel     = "embedder.model"
+	AttrBatchSize = "embedder.batch_size"
+	AttrTextCount = "embedder.text_count"
+	AttrDimension = "embedder.dimension"
+	AttrRetries   = "embedder.retries"
+	AttrAttempt   = "embedder.attempt"
+)
+
+// Tracer 链路追踪钩子，在嵌入调用和每次HTTP请求前后触发，实现需要并发安全
+// 本包不依赖任何追踪库，OpenTelemetry 适配见 otelembedder 子模块
+type Tracer interface {
+	// Begin span 开始时调用，返回的 ctx 会传给内部调用和 End，用于关联父子 span
+	Begin(ctx context.Context, span SpanStart) context.Context
+
+	// End span 结束时调用，ctx 为对应 Begin 的返回值
+	End(ctx context.Context, span SpanEnd)
+}
+
+// SpanStart span 开始时已知的信息
+type SpanStart struct {
+	// Name span 名称，见 Span* 常量
+	Name     string
+	Provider string
+	Model    string
+	// BatchSize 每批文本数，BatchEmbed 为分批大小，其余为文本数
+	BatchSize int
+	// TextCount 本次调用的文本总数
+	TextCount int
+
+	// 以下字段只在 SpanHTTP 中设置
+
+	// Method HTTP 方法
+	Method string
+	// URL 请求地址
+	URL string
+	// Attempt 第几次尝试，从 1 开始
+	Attempt int
+	// Header 即将发送的请求头，可写入 traceparent 等追踪头向服务端传播
+	Header http.Header
+}
+
+// SpanEnd span 结束时的结果
+type SpanEnd struct {
+	// Dimension 返回向量的维度，失败或 Health 时为 0
+	Dimension int
+	// Retries 期间发生的重试次数
+	Retries int
+	// StatusCode HTTP 状态码，只在 SpanHTTP 中设置，连接失败时为 0
+	StatusCode int
+	// Err 调用失败时的错误
+	Err error
+}
+
+// TracingEmbedder 为嵌入调用记录 span 的装饰器
+// 内置 provider 通过 Config.Tracer 另外为每次HTTP请求记录子 span
+type TracingEmbedder struct {
+	inner    Embedder
+	tracer   Tracer
+	provider string
+}
+
+// NewTracingEmbedder 使用追踪钩子包装嵌入服务，provider 作为属性
+func NewTracingEmbedder(inner Embedder, tracer Tracer, provider string) *TracingEmbedder {
+	return &TracingEmbedder{inner: inner, tracer: tracer, provider: provider}
+}
+
+// Embed 批量嵌入多个文本
+func (t *TracingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	ctx, end := t.begin(ctx, SpanEmbed, len(texts), len(texts))
+	embeddings, err := t.inner.Embed(ctx, texts)
+	end(embeddings, err)
+	return embeddings, err
+}
+
+// EmbedSingle 嵌入单个文本
+func (t *TracingEmbedder) EmbedSingle(ctx context.Context, text string) ([]float32, error) {
+	ctx, end := t.begin(ctx, SpanEmbedSingle, 1, 1)
+	embedding, err := t.inner.EmbedSingle(ctx, text)
+	end([][]float32{embedding}, err)
+	return embedding, err
+}
+
+// BatchEmbed 分批处理大量文本
+func (t *TracingEmbedder) BatchEmbed(ctx context.Context, texts []string, batchSize int) ([][]float32, error) {
+	size := batchSize
+	if size <= 0 || size > len(texts) {
+		size = len(texts)
+	}
+	ctx, end := t.begin(ctx, SpanBatchEmbed, size, len(texts))
+	embeddings, err := t.inner.BatchEmbed(ctx, texts, batchSize)
+	end(embeddings, err)
+	return embeddings, err
+}
+
+// EmbedQuery 以查询模式嵌入文本，内部服务不支持时直接嵌入
+func (t *TracingEmbedder) EmbedQuery(ctx context.Context, query string) ([]float32, error) {
+	ctx, end := t.begin(ctx, SpanEmbedQuery, 1, 1)
+	embedding, err := EmbedQuery(ctx, t.inner, query)
+	end([][]float32{embedding}, err)
+	return embedding, err
+}
+
+// EmbedDocuments 以文档模式嵌入多个文本，内部服务不支持时直接嵌入
+func (t *TracingEmbedder) EmbedDocuments(ctx context.Context, documents []string) ([][]float32, error) {
+	ctx, end := t.begin(ctx, SpanEmbedDocuments, len(documents), len(documents))
+	embeddings, err := EmbedDocuments(ctx, t.inner, documents)
+	end(embeddings, err)
+	return embeddings, err
+}
+
+// GetDimension 获取嵌入维度
+func (t *TracingEmbedder) GetDimension() int {
+	return t.inner.GetDimension()
+}
+
+// GetModel 获取模型名称
+func (t *TracingEmbedder) GetModel() string {
+	return t.inner.GetModel()
+}
+
+// Health 健康检查
+func (t *TracingEmbedder) Health(ctx context.Context) error {
+	ctx, end := t.begin(ctx, SpanHealth, 0, 0)
+	err := t.inner.Health(ctx)
+	end(nil, err)
+	return err
+}
+
+// begin 开始一个 span 并统计其间的重试次数（私有方法）
+func (t *TracingEmbedder) begin(ctx context.Context, name string, batchSize, textCount int) (context.Context, func([][]float32, error)) {
+	ctx, state := withSpanState(ctx)
+	ctx = t.tracer.Begin(ctx, SpanStart{
+		Name:      name,
+		Provider:  t.provider,
+		Model:     t.inner.GetModel(),
+		BatchSize: batchSize,
+		TextCount: textCount,
+	})
+
+	return ctx, func(embeddings [][]float32, err error) {
+		result := SpanEnd{Retries: int(state.retries.Load()), Err: err}
+		if err == nil && len(embeddings) > 0 {
+			result.Dimension = len(embeddings[0])
+		}
+		t.tracer.End(ctx, result)
+	}
+}
+
+// spanStateKey 当前 span 状态在 context 中的键
+type spanStateKey struct{}
+
+// attemptKey 当前重试次数在 context 中的键
+type attemptKey struct{}
+
+// spanState 一个 span 期间的重试计数，嵌套 span 通过 parent 逐级累加
+type spanState struct {
+	parent  *spanState
+	retries atomic.Int64
+}
+
+// withSpanState 在 ctx 中挂载新的 span 状态
+func withSpanState(ctx context.Context) (context.Context, *spanState) {
+	parent, _ := ctx.Value(spanStateKey{}).(*spanState)
+	state := &spanState{parent: parent}
+	return context.WithValue(ctx, spanStateKey{}, state), state
+}
+
+// countRetry 为 ctx 中所有进行中的 span 记录一次重试，由 RetryPolicy.Do 调用
+func countRetry(ctx context.Context) {
+	state, _ := ctx.Value(spanStateKey{}).(*spanState)
+	for ; state != nil; state = state.parent {
+		state.retries.Add(1)
+	}
+}
+
+// withAttempt 记录当前是第几次尝试，供 HTTP span 使用
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// attemptFromContext 读取当前尝试次数，不在重试中时为 1
+func attemptFromContext(ctx context.Context) int {
+	if attempt, ok := ctx.Value(attemptKey{}).(int); ok {
+		return attempt
+	}
+	return 1
+}
+
+// traceHTTP 为单次HTTP请求开始 span，返回绑定 span 的请求和结束回调
+// tracer 为 nil 时原样返回请求
+func traceHTTP(tracer Tracer, provider, model string, req *http.Request) (*http.Request, func(*http.Response, error)) {
+	if tracer == nil {
+		return req, func(*http.Response, error) {}
+	}
+
+	ctx := tracer.Begin(req.Context(), SpanStart{
+		Name:     SpanHTTP,
+		Provider: provider,
+		Model:    model,
+		Method:   req.Method,
+		URL:      req.URL.String(),
+		Attempt:  attemptFromContext(req.Context()),
+		Header:   req.Header,
+	})
+
+	return req.WithContext(ctx), func(resp *http.Response, err error) {
+		result := SpanEnd{Err: err}
+		if resp != nil {
+			result.StatusCode = resp.StatusCode
+		}
+		tracer.End(ctx, result)
+	}
+}
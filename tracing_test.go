@@ -0,0 +1,159 @@
+package embedder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordedSpan 测试用的 span 记录
+type recordedSpan struct {
+	start  SpanStart
+	end    SpanEnd
+	parent string
+}
+
+// spanNameKey 当前 span 名称在 context 中的键
+type spanNameKey struct{}
+
+// recordingTracer 记录所有 span 的测试追踪钩子
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (r *recordingTracer) Begin(ctx context.Context, start SpanStart) context.Context {
+	parent, _ := ctx.Value(spanNameKey{}).(string)
+	if start.Header != nil {
+		start.Header.Set("Traceparent", "trace-"+start.Name)
+	}
+	span := &recordedSpan{start: start, parent: parent}
+
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+
+	ctx = context.WithValue(ctx, spanNameKey{}, start.Name)
+	return context.WithValue(ctx, r, span)
+}
+
+func (r *recordingTracer) End(ctx context.Context, end SpanEnd) {
+	span := ctx.Value(r).(*recordedSpan)
+	r.mu.Lock()
+	span.end = end
+	r.mu.Unlock()
+}
+
+func TestTracingEmbedderWithProvider(t *testing.T) {
+	var calls int
+	var traceparents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		traceparents = append(traceparents, r.Header.Get("Traceparent"))
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"embeddings": [][]float64{{1, 2, 3}}})
+	}))
+	defer server.Close()
+
+	tracer := &recordingTracer{}
+	e, err := New("ollama").
+		WithBaseURL(server.URL).
+		WithModel("trace-test").
+		WithRetry(RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}).
+		WithOption(OptionLazy, true).
+		WithTracer(tracer).
+		Build()
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	if _, err := e.EmbedSingle(context.Background(), "hello"); err != nil {
+		t.Fatalf("EmbedSingle failed: %v", err)
+	}
+
+	if len(tracer.spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(tracer.spans))
+	}
+
+	root := tracer.spans[0]
+	if root.start.Name != SpanEmbedSingle || root.start.Provider != "ollama" || root.start.Model != "trace-test" {
+		t.Errorf("Unexpected root span: %+v", root.start)
+	}
+	if root.start.TextCount != 1 || root.end.Dimension != 3 || root.end.Retries != 1 || root.end.Err != nil {
+		t.Errorf("Unexpected root span result: %+v %+v", root.start, root.end)
+	}
+
+	for i, span := range tracer.spans[1:] {
+		if span.start.Name != SpanHTTP || span.parent != SpanEmbedSingle {
+			t.Errorf("Expected HTTP child span, got %s under %q", span.start.Name, span.parent)
+		}
+		if span.start.Method != "POST" || span.start.Attempt != i+1 {
+			t.Errorf("Unexpected HTTP span: %+v", span.start)
+		}
+	}
+	if tracer.spans[1].end.StatusCode != http.StatusServiceUnavailable || tracer.spans[1].end.Err == nil {
+		t.Errorf("Expected failed first attempt, got %+v", tracer.spans[1].end)
+	}
+	if tracer.spans[2].end.StatusCode != http.StatusOK || tracer.spans[2].end.Err != nil {
+		t.Errorf("Expected successful second attempt, got %+v", tracer.spans[2].end)
+	}
+
+	for _, header := range traceparents {
+		if header != "trace-"+SpanHTTP {
+			t.Errorf("Expected trace header to be propagated, got %q", header)
+		}
+	}
+}
+
+func TestTracingEmbedderMethods(t *testing.T) {
+	tracer := &recordingTracer{}
+	inner := &countingEmbedder{}
+	e := NewTracingEmbedder(NewInstructionEmbedder(inner, PromptTemplate{Query: "q: "}), tracer, "mock")
+	ctx := context.Background()
+
+	if _, err := e.BatchEmbed(ctx, []string{"a", "b", "c"}, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := EmbedQuery(ctx, e, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Health(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SpanStart{
+		{Name: SpanBatchEmbed, Provider: "mock", Model: "mock-model", BatchSize: 2, TextCount: 3},
+		{Name: SpanEmbedQuery, Provider: "mock", Model: "mock-model", BatchSize: 1, TextCount: 1},
+		{Name: SpanHealth, Provider: "mock", Model: "mock-model"},
+	}
+	if len(tracer.spans) != len(want) {
+		t.Fatalf("Expected %d spans, got %d", len(want), len(tracer.spans))
+	}
+	for i, span := range tracer.spans {
+		if span.start.Name != want[i].Name || span.start.BatchSize != want[i].BatchSize || span.start.TextCount != want[i].TextCount {
+			t.Errorf("Span %d: expected %+v, got %+v", i, want[i], span.start)
+		}
+	}
+	if tracer.spans[0].end.Dimension != 2 || tracer.spans[2].end.Dimension != 0 {
+		t.Errorf("Unexpected dimensions: %+v %+v", tracer.spans[0].end, tracer.spans[2].end)
+	}
+	if last := inner.received[len(inner.received)-1]; last != "q: hello" {
+		t.Errorf("Expected query prefix to be applied, got %q", last)
+	}
+
+	err := &ProviderError{Provider: "mock", Index: -1, Kind: ErrUnavailable}
+	failing := NewTracingEmbedder(&errorEmbedder{err: err}, tracer, "mock")
+	if _, got := failing.Embed(ctx, []string{"a"}); got != err {
+		t.Fatalf("Expected error to pass through, got %v", got)
+	}
+	if last := tracer.spans[len(tracer.spans)-1]; last.end.Err != err || last.end.Dimension != 0 {
+		t.Errorf("Expected error on span, got %+v", last.end)
+	}
+}
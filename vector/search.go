@@ -0,0 +1,189 @@
+package vector
+
+import (
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// Result 检索结果
+type Result struct {
+	// Index 候选向量的下标
+	Index int
+	// Score 相似度得分，越大越相似
+	Score float32
+}
+
+// TopK 返回与 query 最相似的 k 个向量，按得分从高到低排序
+// 使用大小为 k 的最小堆，时间复杂度 O(n log k)
+func TopK(query []float32, vectors [][]float32, k int, metric Metric) []Result {
+	return TopKFiltered(query, vectors, k, metric, nil)
+}
+
+// TopKFiltered 与 TopK 相同，但只考虑 accept 返回 true 的向量，accept 为 nil 时考虑全部
+// 与 query 长度不同的向量被跳过，结果可能少于 k 个
+func TopKFiltered(query []float32, vectors [][]float32, k int, metric Metric, accept func(i int) bool) []Result {
+	if k <= 0 || len(vectors) == 0 {
+		return nil
+	}
+	if k > len(vectors) {
+		k = len(vectors)
+	}
+
+	h := make(resultHeap, 0, k)
+	for i, v := range vectors {
+		if accept != nil && !accept(i) {
+			continue
+		}
+		score := metric.Score(query, v)
+		if score != score {
+			// 维度不同的向量得分为 NaN，不参与排序
+			continue
+		}
+		if len(h) < k {
+			h.push(Result{Index: i, Score: score})
+		} else if score > h[0].Score {
+			h[0] = Result{Index: i, Score: score}
+			h.down(0)
+		}
+	}
+
+	sort.Slice(h, func(i, j int) bool {
+		if h[i].Score != h[j].Score {
+			return h[i].Score > h[j].Score
+		}
+		return h[i].Index < h[j].Index
+	})
+	return h
+}
+
+// BatchTopK 对多个查询并发执行 TopK，结果与 queries 一一对应
+func BatchTopK(queries, vectors [][]float32, k int, metric Metric) [][]Result {
+	results := make([][]Result, len(queries))
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(queries) {
+		workers = len(queries)
+	}
+
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = TopK(queries[i], vectors, k, metric)
+			}
+		}()
+	}
+	for i := range queries {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// MMR 最大边际相关性重排，从候选中选出 k 个既相关又多样的向量
+// lambda 取值 [0,1]，越大越偏向相关性，越小越偏向多样性；相似度使用余弦
+// 与 query 长度不同的候选被跳过，结果可能少于 k 个
+func MMR(query []float32, candidates [][]float32, k int, lambda float32) []Result {
+	if k <= 0 || len(candidates) == 0 {
+		return nil
+	}
+
+	// 与 query 长度不同的候选视为已选，不出现在结果中
+	selected := make([]bool, len(candidates))
+	relevance := make([]float32, len(candidates))
+	valid := 0
+	for i, c := range candidates {
+		relevance[i] = CosineSimilarity(query, c)
+		if relevance[i] != relevance[i] {
+			selected[i] = true
+			continue
+		}
+		valid++
+	}
+	if k > valid {
+		k = valid
+	}
+
+	// maxSim[i] 为候选 i 与已选集合的最大相似度
+	maxSim := make([]float32, len(candidates))
+	for i := range maxSim {
+		maxSim[i] = float32(math.Inf(-1))
+	}
+	results := make([]Result, 0, k)
+
+	for len(results) < k {
+		best := -1
+		var bestScore float32
+		for i := range candidates {
+			if selected[i] {
+				continue
+			}
+			score := lambda * relevance[i]
+			if len(results) > 0 {
+				score -= (1 - lambda) * maxSim[i]
+			}
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		selected[best] = true
+		results = append(results, Result{Index: best, Score: bestScore})
+
+		for i, c := range candidates {
+			if selected[i] {
+				continue
+			}
+			if sim := CosineSimilarity(candidates[best], c); sim > maxSim[i] {
+				maxSim[i] = sim
+			}
+		}
+	}
+
+	return results
+}
+
+// resultHeap 按得分的最小堆，堆顶为当前 top-k 中得分最低的结果
+type resultHeap []Result
+
+func (h *resultHeap) push(r Result) {
+	*h = append(*h, r)
+	h.up(len(*h) - 1)
+}
+
+func (h resultHeap) up(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if h[parent].Score <= h[i].Score {
+			break
+		}
+		h[parent], h[i] = h[i], h[parent]
+		i = parent
+	}
+}
+
+func (h resultHeap) down(i int) {
+	n := len(h)
+	for {
+		smallest := i
+		left, right := 2*i+1, 2*i+2
+		if left < n && h[left].Score < h[smallest].Score {
+			smallest = left
+		}
+		if right < n && h[right].Score < h[smallest].Score {
+			smallest = right
+		}
+		if smallest == i {
+			return
+		}
+		h[i], h[smallest] = h[smallest], h[i]
+		i = smallest
+	}
+}
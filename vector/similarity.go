@@ -0,0 +1,136 @@
+// Package vector 提供嵌入向量的相似度计算、top-k 检索和 MMR 重排
+// 直接作用于 Embedder.Embed 返回的 []float32，热路径上不分配内存
+//
+// 两个向量长度不同（如来自不同模型）时相似度函数返回 NaN，
+// TopK、MMR 会跳过得分为 NaN 的候选
+package vector
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Metric 相似度度量
+type Metric string
+
+const (
+	// Cosine 余弦相似度
+	Cosine Metric = "cosine"
+	// Dot 点积，适用于已归一化的向量
+	Dot Metric = "dot"
+	// Euclidean 欧氏距离，得分取负值使其“越大越相似”
+	Euclidean Metric = "euclidean"
+)
+
+// Score 按度量计算相似度得分，得分越大越相似
+func (m Metric) Score(a, b []float32) float32 {
+	switch m {
+	case Dot:
+		return DotProduct(a, b)
+	case Euclidean:
+		return -EuclideanDistance(a, b)
+	default:
+		return CosineSimilarity(a, b)
+	}
+}
+
+// nan 长度不同时的相似度
+var nan = float32(math.NaN())
+
+// DotProduct 计算点积，长度不同时返回 NaN
+// 循环按 4 路展开，便于编译器生成向量化指令
+func DotProduct(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return nan
+	}
+	n := len(a)
+	b = b[:n] // 消除边界检查
+
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= n; i += 4 {
+		s0 += a[i] * b[i]
+		s1 += a[i+1] * b[i+1]
+		s2 += a[i+2] * b[i+2]
+		s3 += a[i+3] * b[i+3]
+	}
+	for ; i < n; i++ {
+		s0 += a[i] * b[i]
+	}
+	return s0 + s1 + s2 + s3
+}
+
+// Norm 计算L2范数
+func Norm(a []float32) float32 {
+	return float32(math.Sqrt(float64(DotProduct(a, a))))
+}
+
+// CosineSimilarity 计算余弦相似度，任一向量为零向量时返回 0，长度不同时返回 NaN
+func CosineSimilarity(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return nan
+	}
+
+	var dot, na, nb float32
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / float32(math.Sqrt(float64(na)*float64(nb)))
+}
+
+// SquaredEuclidean 计算欧氏距离的平方，比较大小时可省去开方，长度不同时返回 NaN
+func SquaredEuclidean(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return nan
+	}
+	n := len(a)
+	b = b[:n] // 消除边界检查
+
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= n; i += 4 {
+		d0 := a[i] - b[i]
+		d1 := a[i+1] - b[i+1]
+		d2 := a[i+2] - b[i+2]
+		d3 := a[i+3] - b[i+3]
+		s0 += d0 * d0
+		s1 += d1 * d1
+		s2 += d2 * d2
+		s3 += d3 * d3
+	}
+	for ; i < n; i++ {
+		d := a[i] - b[i]
+		s0 += d * d
+	}
+	return s0 + s1 + s2 + s3
+}
+
+// EuclideanDistance 计算欧氏距离，长度不同时返回 NaN
+func EuclideanDistance(a, b []float32) float32 {
+	return float32(math.Sqrt(float64(SquaredEuclidean(a, b))))
+}
+
+// Hamming 计算二值量化向量的汉明距离
+func Hamming(a, b []byte) int {
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+
+	distance := 0
+	i := 0
+	for ; i+8 <= n; i += 8 {
+		x := uint64(a[i]^b[i]) | uint64(a[i+1]^b[i+1])<<8 | uint64(a[i+2]^b[i+2])<<16 | uint64(a[i+3]^b[i+3])<<24 |
+			uint64(a[i+4]^b[i+4])<<32 | uint64(a[i+5]^b[i+5])<<40 | uint64(a[i+6]^b[i+6])<<48 | uint64(a[i+7]^b[i+7])<<56
+		distance += bits.OnesCount64(x)
+	}
+	for ; i < n; i++ {
+		distance += bits.OnesCount8(a[i] ^ b[i])
+	}
+	return distance
+}
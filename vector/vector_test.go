@@ -0,0 +1,120 @@
+package vector
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func approxEqual(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-5
+}
+
+func TestSimilarityFunctions(t *testing.T) {
+	a := []float32{1, 2, 3, 4, 5}
+	b := []float32{5, 4, 3, 2, 1}
+
+	if got := DotProduct(a, b); got != 35 {
+		t.Errorf("DotProduct = %v, want 35", got)
+	}
+	if got := SquaredEuclidean(a, b); got != 40 {
+		t.Errorf("SquaredEuclidean = %v, want 40", got)
+	}
+	if got := CosineSimilarity(a, b); !approxEqual(got, 35.0/55.0) {
+		t.Errorf("CosineSimilarity = %v, want %v", got, 35.0/55.0)
+	}
+	if got := CosineSimilarity(a, make([]float32, 5)); got != 0 {
+		t.Errorf("CosineSimilarity with zero vector = %v, want 0", got)
+	}
+	if got := Hamming([]byte{0xff, 0, 0, 0, 0, 0, 0, 0, 0x0f}, make([]byte, 9)); got != 12 {
+		t.Errorf("Hamming = %v, want 12", got)
+	}
+}
+
+func TestSimilarityLengthMismatch(t *testing.T) {
+	a := []float32{1, 2, 3}
+	b := []float32{1, 2}
+
+	for name, got := range map[string]float32{
+		"DotProduct":        DotProduct(a, b),
+		"CosineSimilarity":  CosineSimilarity(a, b),
+		"EuclideanDistance": EuclideanDistance(a, b),
+	} {
+		if !math.IsNaN(float64(got)) {
+			t.Errorf("%s = %v, want NaN for mismatched lengths", name, got)
+		}
+	}
+
+	vectors := [][]float32{{1, 2}, {1, 2, 3}, {3, 2, 1}}
+	results := TopK(a, vectors, 3, Cosine)
+	if len(results) != 2 || results[0].Index != 1 || results[1].Index != 2 {
+		t.Errorf("Expected TopK to skip the mismatched vector, got %v", results)
+	}
+	if results := MMR(a, vectors, 3, 0.5); len(results) != 2 {
+		t.Errorf("Expected MMR to skip the mismatched vector, got %v", results)
+	}
+}
+
+func TestSimilarityAllocations(t *testing.T) {
+	a := make([]float32, 768)
+	b := make([]float32, 768)
+	allocs := testing.AllocsPerRun(100, func() {
+		DotProduct(a, b)
+		CosineSimilarity(a, b)
+		EuclideanDistance(a, b)
+	})
+	if allocs != 0 {
+		t.Errorf("Expected no allocations, got %v", allocs)
+	}
+}
+
+func TestTopKMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	vectors := make([][]float32, 200)
+	for i := range vectors {
+		vectors[i] = make([]float32, 16)
+		for j := range vectors[i] {
+			vectors[i][j] = rng.Float32()*2 - 1
+		}
+	}
+	query := vectors[7]
+
+	for _, metric := range []Metric{Cosine, Dot, Euclidean} {
+		results := TopK(query, vectors, 10, metric)
+
+		all := make([]Result, len(vectors))
+		for i, v := range vectors {
+			all[i] = Result{Index: i, Score: metric.Score(query, v)}
+		}
+		sort.Slice(all, func(i, j int) bool { return all[i].Score > all[j].Score })
+
+		for i := range results {
+			if results[i].Index != all[i].Index {
+				t.Fatalf("%s: result %d = %v, want %v", metric, i, results[i], all[i])
+			}
+		}
+	}
+
+	batch := BatchTopK([][]float32{vectors[3], vectors[9]}, vectors, 1, Cosine)
+	if batch[0][0].Index != 3 || batch[1][0].Index != 9 {
+		t.Errorf("BatchTopK returned %v", batch)
+	}
+}
+
+func TestMMRPrefersDiversity(t *testing.T) {
+	query := []float32{1, 0}
+	candidates := [][]float32{
+		{1, 0},
+		{0.99, 0.01},
+		{0.7, 0.7},
+	}
+
+	if results := MMR(query, candidates, 2, 1); results[1].Index != 1 {
+		t.Errorf("With lambda=1 expected pure relevance order, got %v", results)
+	}
+	results := MMR(query, candidates, 2, 0.3)
+	if results[0].Index != 0 || results[1].Index != 2 {
+		t.Errorf("Expected MMR to pick diverse candidate 2 second, got %v", results)
+	}
+}